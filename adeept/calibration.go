@@ -0,0 +1,199 @@
+package adeept
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/pose"
+	"github.com/egonelbre/hexapod/servo"
+)
+
+// Calibration describes how the servos are mounted, by leg name.
+//
+// calibration.json contains the uncalibrated defaults.
+type Calibration struct {
+	Legs map[string]*LegCalibration `json:"legs"`
+}
+
+// LegCalibration describes the servos of a single leg.
+type LegCalibration struct {
+	Coxa  HingeCalibration `json:"coxa"`
+	Femur HingeCalibration `json:"femur"`
+	Tibia HingeCalibration `json:"tibia"`
+}
+
+// Hinges returns the calibrations in the same order as leg.Hinges().
+func (leg *LegCalibration) Hinges() []*HingeCalibration {
+	return []*HingeCalibration{&leg.Coxa, &leg.Femur, &leg.Tibia}
+}
+
+// HingeCalibration describes a single servo.
+type HingeCalibration struct {
+	// Channel is the PWM channel of the servo.
+	Channel int `json:"channel"`
+	// Trim is added to the hinge angle, in degrees, it corrects
+	// for how the servo horn is mounted.
+	Trim float32 `json:"trim"`
+	// Sign is -1 when the servo turns opposite to the hinge.
+	Sign int `json:"sign"`
+	// MinPulse and MaxPulse limit the pulse width in microseconds.
+	MinPulse int `json:"min_pulse"`
+	MaxPulse int `json:"max_pulse"`
+}
+
+// DefaultCalibration returns an uncalibrated robot, where the servos are
+// on consecutive channels in the order of body.Legs().
+func DefaultCalibration() *Calibration {
+	cal := &Calibration{Legs: map[string]*LegCalibration{}}
+	channel := 0
+	for _, leg := range ZeroPose().Legs() {
+		legcal := &LegCalibration{}
+		for _, hinge := range legcal.Hinges() {
+			*hinge = HingeCalibration{
+				Channel:  channel,
+				Sign:     1,
+				MinPulse: int(servo.SG92R.Min / time.Microsecond),
+				MaxPulse: int(servo.SG92R.Max / time.Microsecond),
+			}
+			channel++
+		}
+		cal.Legs[leg.Name] = legcal
+	}
+	return cal
+}
+
+// Load loads the zero pose with the calibration from path.
+func Load(path string) (*pose.Body, *Calibration, error) {
+	body := ZeroPose()
+	cal, err := LoadCalibration(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cal.Validate(body); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return body, cal, nil
+}
+
+// LoadCalibration reads calibration from a JSON file.
+func LoadCalibration(path string) (*Calibration, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	cal, err := ReadCalibration(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cal, nil
+}
+
+// ReadCalibration reads calibration as JSON.
+func ReadCalibration(r io.Reader) (*Calibration, error) {
+	cal := &Calibration{}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cal); err != nil {
+		return nil, err
+	}
+	return cal, nil
+}
+
+// SaveCalibration writes calibration to a JSON file.
+func SaveCalibration(path string, cal *Calibration) error {
+	data, err := json.MarshalIndent(cal, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Validate checks that every hinge of body is calibrated and that the
+// channels are not shared.
+func (cal *Calibration) Validate(body *pose.Body) error {
+	var errs []error
+	channels := map[int]string{}
+	for _, leg := range body.Legs() {
+		legcal, ok := cal.Legs[leg.Name]
+		if !ok || legcal == nil {
+			errs = append(errs, fmt.Errorf("%s: missing", leg.Name))
+			continue
+		}
+
+		for i, hinge := range legcal.Hinges() {
+			name := fmt.Sprintf("%s %s", leg.Name, hingeNames[i])
+			if hinge.Channel < 0 {
+				errs = append(errs, fmt.Errorf("%s: invalid channel %d", name, hinge.Channel))
+			} else if other, used := channels[hinge.Channel]; used {
+				errs = append(errs, fmt.Errorf("%s: channel %d already used by %s", name, hinge.Channel, other))
+			}
+			channels[hinge.Channel] = name
+
+			if hinge.Sign != 1 && hinge.Sign != -1 {
+				errs = append(errs, fmt.Errorf("%s: sign must be 1 or -1, got %d", name, hinge.Sign))
+			}
+			if hinge.MinPulse <= 0 || hinge.MinPulse >= hinge.MaxPulse {
+				errs = append(errs, fmt.Errorf("%s: invalid pulse range %dµs-%dµs", name, hinge.MinPulse, hinge.MaxPulse))
+			} else if s := hinge.Servo(); s.Pulse(0) <= s.Min || s.Pulse(0) >= s.Max {
+				errs = append(errs, fmt.Errorf("%s: trim %v° outside of the pulse range", name, hinge.Trim))
+			}
+		}
+	}
+
+	for name := range cal.Legs {
+		if !hasLeg(body, name) {
+			errs = append(errs, fmt.Errorf("%s: unknown leg", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Apply validates the calibration and configures servos for body.
+func (cal *Calibration) Apply(body *pose.Body, robot *servo.Robot) error {
+	if err := cal.Validate(body); err != nil {
+		return err
+	}
+	for i, leg := range body.Legs() {
+		for k, hinge := range cal.Legs[leg.Name].Hinges() {
+			robot.Servos[i][k] = hinge.Servo()
+		}
+	}
+	return nil
+}
+
+// Servo returns the mapping from hinge angle to pulse for a SG92R.
+func (hinge *HingeCalibration) Servo() servo.Servo {
+	s := servo.SG92R
+	s.Channel = hinge.Channel
+	s.PerRadian *= time.Duration(hinge.Sign)
+	s.Center += time.Duration(float32(s.PerRadian) * hinge.Trim * float32(g.DegToRad))
+	s.Min, s.Max = hinge.servoMin(), hinge.servoMax()
+	return s
+}
+
+func (hinge *HingeCalibration) servoMin() time.Duration {
+	return time.Duration(hinge.MinPulse) * time.Microsecond
+}
+
+func (hinge *HingeCalibration) servoMax() time.Duration {
+	return time.Duration(hinge.MaxPulse) * time.Microsecond
+}
+
+var hingeNames = [3]string{"coxa", "femur", "tibia"}
+
+// hasLeg checks whether body has a leg with the name.
+func hasLeg(body *pose.Body, name string) bool {
+	for _, leg := range body.Legs() {
+		if leg.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package adeept_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/servo"
+)
+
+func TestCalibrationFile(t *testing.T) {
+	cal := adeept.DefaultCalibration()
+	cal.Legs["LM"].Femur.Trim = -5
+	cal.Legs["LM"].Femur.Sign = -1
+
+	path := filepath.Join(t.TempDir(), "calibration.json")
+	if err := adeept.SaveCalibration(path, cal); err != nil {
+		t.Fatal(err)
+	}
+
+	body, loaded, err := adeept.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := *loaded.Legs["LM"]; got != *cal.Legs["LM"] {
+		t.Errorf("got %v; exp %v", got, *cal.Legs["LM"])
+	}
+
+	robot := &servo.Robot{}
+	if err := loaded.Apply(body, robot); err != nil {
+		t.Fatal(err)
+	}
+
+	// LM is the fifth leg
+	femur := robot.Servos[4][1]
+	if femur.Channel != 13 {
+		t.Errorf("channel got %d; exp 13", femur.Channel)
+	}
+	// inverted, 5° trim is 55.6µs
+	if got, exp := femur.Pulse(g.Tau/8), (1500-500+56)*time.Microsecond; (got - exp).Abs() > time.Microsecond {
+		t.Errorf("pulse got %v; exp %v", got, exp)
+	}
+}
+
+func TestCalibrationValidate(t *testing.T) {
+	body := adeept.ZeroPose()
+	if err := adeept.DefaultCalibration().Validate(body); err != nil {
+		t.Fatal(err)
+	}
+
+	cal := adeept.DefaultCalibration()
+	delete(cal.Legs, "RB")
+	cal.Legs["RF"].Coxa.Channel = cal.Legs["RF"].Tibia.Channel
+	cal.Legs["LF"].Tibia.Sign = 0
+	cal.Legs["LB"].Coxa.MaxPulse = 100
+	cal.Legs["LM"].Femur.Trim = 120
+
+	err := cal.Validate(body)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, exp := range []string{"RB: missing", "channel 2 already used", "LF tibia: sign", "LB coxa: invalid pulse range", "LM femur: trim"} {
+		if !strings.Contains(err.Error(), exp) {
+			t.Errorf("missing %q in %v", exp, err)
+		}
+	}
+
+	if _, err := adeept.ReadCalibration(strings.NewReader(`{"legs": {}, "extra": 1}`)); err == nil {
+		t.Errorf("expected an error for unknown fields")
+	}
+}
@@ -6,30 +6,43 @@ import (
 )
 
 const (
-	legRot = g.Tau / 8           //TODO: fix
+	legRot = g.Tau / 8           // corner legs point diagonally outwards
 	legY   = 28*g.MM - 45*g.MM/2 // relative to body center
 
-	femurAngleOffset = 0 // + g.Tau/8
-	tibiaAngleOffset = 0 // + g.Tau/8
-
 	servo_sg92r_sec_per60deg = 0.1
 	servo_sg92r_speed        = 60.0 * g.DegToRad / servo_sg92r_sec_per60deg
 )
 
+// Estimated masses, the body includes the batteries and the Coxa servos,
+// the links include the servo of the next hinge.
+const (
+	bodyMass  = 0.300
+	headMass  = 0.025
+	coxaMass  = 0.012
+	femurMass = 0.015
+	tibiaMass = 0.008
+)
+
+// ZeroPose returns the Adeept hexapod, robot.json contains the same description.
 func ZeroPose() *pose.Body {
 	return &pose.Body{
-		Size:   g.Vec{105 * g.MM, 45 * g.MM, 105 * g.MM},
-		Origin: g.Vec{0, 22 * g.MM, 0},
+		Size:   g.Vec{X: 105 * g.MM, Y: 45 * g.MM, Z: 105 * g.MM},
+		Origin: g.Vec{Y: 22 * g.MM},
+		Mass: pose.Mass{
+			Kilograms: bodyMass,
+			Center:    g.Vec{Y: -5 * g.MM},
+		},
 		Head: pose.Head{
-			Offset: g.Vec{63 * g.MM, 20 * g.MM, 0},
+			Offset: g.Vec{X: 63 * g.MM, Y: 20 * g.MM},
+			Mass:   pose.Mass{Kilograms: headMass},
 		},
 		Leg: pose.Legs{
-			RF: ZeroLeg("RF", g.Vec{63 * g.MM, legY, +57 * g.MM}, +legRot, 1, 3*g.Tau/6),
-			LF: ZeroLeg("LF", g.Vec{63 * g.MM, legY, -57 * g.MM}, -legRot, -1, 0*g.Tau/6),
-			RM: ZeroLeg("RM", g.Vec{0, legY, +77 * g.MM}, +g.Tau/4, 1, 1*g.Tau/6),
-			LM: ZeroLeg("LM", g.Vec{0, legY, -77 * g.MM}, -g.Tau/4, -1, 4*g.Tau/6),
-			RB: ZeroLeg("RB", g.Vec{-63 * g.MM, legY, +57 * g.MM}, +g.Tau/2-legRot, 1, 5*g.Tau/6),
-			LB: ZeroLeg("LB", g.Vec{-63 * g.MM, legY, -57 * g.MM}, -g.Tau/2+legRot, -1, 2*g.Tau/6),
+			RF: ZeroLeg("RF", g.Vec{X: 63 * g.MM, Y: legY, Z: +57 * g.MM}, +legRot, 1, 3*g.Tau/6),
+			LF: ZeroLeg("LF", g.Vec{X: 63 * g.MM, Y: legY, Z: -57 * g.MM}, -legRot, -1, 0*g.Tau/6),
+			RM: ZeroLeg("RM", g.Vec{Y: legY, Z: +77 * g.MM}, +g.Tau/4, 1, 1*g.Tau/6),
+			LM: ZeroLeg("LM", g.Vec{Y: legY, Z: -77 * g.MM}, -g.Tau/4, -1, 4*g.Tau/6),
+			RB: ZeroLeg("RB", g.Vec{X: -63 * g.MM, Y: legY, Z: +57 * g.MM}, +g.Tau/2-legRot, 1, 5*g.Tau/6),
+			LB: ZeroLeg("LB", g.Vec{X: -63 * g.MM, Y: legY, Z: -57 * g.MM}, -g.Tau/2+legRot, -1, 2*g.Tau/6),
 		},
 	}
 }
@@ -37,28 +50,35 @@ func ZeroPose() *pose.Body {
 func ZeroLeg(name string, offset g.Vec, zero g.Radians, side g.Radians, phase g.Radians) pose.Leg {
 	target := offset
 	target.Y = 0
+	if phase != 0 {
+		// avoid -0 in the saved description
+		phase = -phase
+	}
 	return pose.Leg{
 		Name:   name,
-		Phase:  -phase,
+		Phase:  phase,
 		Offset: offset,
 		Coxa: pose.Hinge{
 			Axis:   pose.Y,
 			Zero:   zero,
 			Length: 12 * g.MM,
 			Speed:  servo_sg92r_speed,
-			Range:  pose.HingeRange{side * -g.Tau / 4, side * g.Tau / 4},
+			Mass:   pose.Mass{Kilograms: coxaMass, Center: g.Vec{X: 8 * g.MM}},
+			Range:  pose.HingeRange{Min: side * -g.Tau / 4, Max: side * g.Tau / 4},
 		},
 		Femur: pose.Hinge{
 			Axis:   pose.Z,
 			Length: 38 * g.MM,
 			Speed:  servo_sg92r_speed,
-			Range:  pose.HingeRange{g.Tau/4 + femurAngleOffset, -g.Tau/4 + femurAngleOffset},
+			Mass:   pose.Mass{Kilograms: femurMass, Center: g.Vec{X: 25 * g.MM}},
+			Range:  pose.HingeRange{Min: g.Tau / 4, Max: -g.Tau / 4},
 		},
 		Tibia: pose.Hinge{
 			Axis:   pose.Z,
 			Length: 50 * g.MM,
 			Speed:  servo_sg92r_speed,
-			Range:  pose.HingeRange{g.Tau/4 + tibiaAngleOffset, -g.Tau/4 + tibiaAngleOffset},
+			Mass:   pose.Mass{Kilograms: tibiaMass, Center: g.Vec{X: 20 * g.MM}},
+			Range:  pose.HingeRange{Min: g.Tau / 4, Max: -g.Tau / 4},
 		},
 		IK: pose.LegIK{
 			Origin: offset,
@@ -0,0 +1,133 @@
+// package balance shifts the body to keep it statically stable while lifting legs
+package balance
+
+import (
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/pose"
+	"github.com/egonelbre/hexapod/stability"
+)
+
+// Lift is an upcoming lift-off of a leg.
+type Lift struct {
+	Leg *pose.Leg
+	// In is the time until lift-off in seconds, zero when already in the air.
+	In float32
+}
+
+// Planner moves the body away from the legs that are about to be lifted.
+type Planner struct {
+	// Margin is the desired distance of the center of mass from the
+	// edges of the support polygon.
+	Margin g.Length
+	// Speed is the maximum speed of shifting, per second.
+	Speed g.Length
+	// Lookahead is how early the body starts shifting before a lift, in seconds.
+	Lookahead float32
+	// Tilt leans the body towards the shift, per meter.
+	Tilt g.Radians
+
+	// Shift is the current offset of the body from the neutral pose.
+	Shift g.Vec
+}
+
+// NewPlanner creates a planner with default settings.
+func NewPlanner() *Planner {
+	return &Planner{
+		Margin:    10 * g.MM,
+		Speed:     50 * g.MM,
+		Lookahead: 0.3,
+		Tilt:      g.Tau / 4,
+	}
+}
+
+// Update shifts the body from the neutral pose set by the control.
+//
+// The support polygon is formed from planted leg targets, excluding
+// the legs that lift within Lookahead.
+func (planner *Planner) Update(body *pose.Body, lifts []Lift, dt float32) {
+	var feet []g.Vec
+	for _, leg := range body.Legs() {
+		if leg.IK.Planted && !lifting(leg, lifts, planner.Lookahead) {
+			feet = append(feet, leg.IK.Target)
+		}
+	}
+
+	// Legs are mostly fixed to the ground, hence the center of mass moves
+	// less than the body.
+	fraction := carried(body)
+
+	// hinge angles are solved for the shifted body
+	origin := body.Origin
+	body.Origin = origin.Add(planner.Shift)
+	center := stability.CenterOfMass(body).Sub(planner.Shift.Scale(fraction))
+	body.Origin = origin
+
+	target := Target(g.ConvexHull(feet), center, planner.Margin)
+	desired := target.Sub(center).Scale(1 / fraction)
+	desired.Y = 0
+
+	change := desired.Sub(planner.Shift)
+	if limit := planner.Speed.Scale(dt); change.Length() > limit {
+		change = change.NormalizedTo(limit)
+	}
+	planner.Shift = planner.Shift.Add(change)
+
+	body.Origin = body.Origin.Add(planner.Shift)
+	body.Orient.Pitch += planner.Tilt * planner.Shift.X.Meters()
+	body.Orient.Roll -= planner.Tilt * planner.Shift.Z.Meters()
+}
+
+// Target returns the point closest to center towards the middle of support,
+// that has at least margin distance from the edges.
+func Target(support g.Polygon, center g.Vec, margin g.Length) g.Vec {
+	center.Y = 0
+	if len(support) == 0 || stability.Margin(support, center) >= margin {
+		return center
+	}
+
+	var middle g.Vec
+	for _, p := range support {
+		middle = middle.Add(p)
+	}
+	middle = middle.Scale(1 / float32(len(support)))
+	middle.Y = 0
+	if stability.Margin(support, middle) < margin {
+		return middle
+	}
+
+	low, high := float32(0), float32(1)
+	for i := 0; i < 16; i++ {
+		mid := (low + high) / 2
+		if stability.Margin(support, center.Lerp(middle, mid)) >= margin {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	return center.Lerp(middle, high)
+}
+
+// lifting checks whether leg lifts within lookahead.
+func lifting(leg *pose.Leg, lifts []Lift, lookahead float32) bool {
+	for _, lift := range lifts {
+		if lift.Leg == leg && lift.In <= lookahead {
+			return true
+		}
+	}
+	return false
+}
+
+// carried returns the fraction of the mass that moves with the body.
+func carried(body *pose.Body) float32 {
+	carried := body.Mass.Kilograms + body.Head.Mass.Kilograms
+	total := carried
+	for _, leg := range body.Legs() {
+		for _, hinge := range leg.Hinges() {
+			total += hinge.Mass.Kilograms
+		}
+	}
+	if carried <= 0 || total <= 0 {
+		return 1
+	}
+	return carried / total
+}
@@ -0,0 +1,82 @@
+package balance_test
+
+import (
+	"testing"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/balance"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/gait"
+	"github.com/egonelbre/hexapod/ik/legik"
+	"github.com/egonelbre/hexapod/stability"
+)
+
+func TestTarget(t *testing.T) {
+	support := g.ConvexHull([]g.Vec{
+		{X: 0, Z: 0},
+		{X: 100 * g.MM, Z: 0},
+		{X: 0, Z: 100 * g.MM},
+		{X: 100 * g.MM, Z: 100 * g.MM},
+	})
+
+	type Case struct {
+		Center, Target g.Vec
+	}
+	cases := []Case{
+		{g.Vec{X: 50 * g.MM, Z: 50 * g.MM}, g.Vec{X: 50 * g.MM, Z: 50 * g.MM}},
+		{g.Vec{X: 50 * g.MM, Z: 5 * g.MM}, g.Vec{X: 50 * g.MM, Z: 10 * g.MM}},
+		{g.Vec{X: 50 * g.MM, Z: -50 * g.MM}, g.Vec{X: 50 * g.MM, Z: 10 * g.MM}},
+	}
+	for _, c := range cases {
+		got := balance.Target(support, c.Center, 10*g.MM)
+		if d := got.Distance(c.Target); d > 0.01*g.MM {
+			t.Errorf("%v: got %v; exp %v", c.Center, got, c.Target)
+		}
+	}
+}
+
+func TestLiftStaysStable(t *testing.T) {
+	body := adeept.ZeroPose()
+	neutral := g.Vec{Y: body.Size.Y}
+
+	planner := balance.NewPlanner()
+	lifted := []string{"LF", "RB", "RF", "LB"}
+
+	// with the middle legs in the air, lifting any of the corner
+	// legs is unstable without shifting the body
+	const dt = 1.0 / 60
+	for _, name := range lifted {
+		var lift balance.Lift
+		for _, leg := range body.Legs() {
+			if leg.Name == name {
+				lift.Leg = leg
+			}
+		}
+
+		for frame := 0; frame < 120; frame++ {
+			lift.In = 1 - float32(frame)*dt
+
+			body.Origin, body.Orient = neutral, g.Orient{}
+			for _, leg := range body.Legs() {
+				leg.IK.Target = gait.Home(leg)
+				leg.IK.Planted = leg.Name[1] != 'M' && (lift.In > 0 || leg != lift.Leg)
+				if !leg.IK.Planted {
+					leg.IK.Target.Y = 20 * g.MM
+				}
+			}
+
+			before := planner.Shift
+			planner.Update(body, []balance.Lift{lift}, dt)
+			legik.Solve(body)
+
+			if d := planner.Shift.Distance(before); d > planner.Speed.Scale(dt)+0.01*g.MM {
+				t.Fatalf("%s: shifted %.3fmm in a frame", name, d.Millimeters())
+			}
+			if lift.In <= 0 {
+				if analysis := stability.Analyze(body); analysis.Margin < planner.Margin/2 {
+					t.Errorf("%s: frame %d, margin %.3fmm", name, frame, analysis.Margin.Millimeters())
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gen2brain/raylib-go/raygui"
+	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/fk"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+// Calibrate holds the legs in the air at fixed hinge angles, such that
+// servo trims can be adjusted until the robot matches the model.
+type Calibrate struct {
+	Path        string
+	Calibration *adeept.Calibration
+
+	Leg   int
+	Hinge int
+	// Angle is the angle of the selected hinge, others are at zero.
+	Angle g.Radians
+
+	Status string
+}
+
+// NewCalibrate loads calibration for body from path, using defaults when
+// the file does not exist or is invalid.
+//
+// It returns nil when body does not have the Adeept servo layout.
+func NewCalibrate(body *pose.Body, path string) *Calibrate {
+	if adeept.DefaultCalibration().Validate(body) != nil {
+		return nil
+	}
+	calibrate := &Calibrate{Path: path}
+
+	cal, err := adeept.LoadCalibration(path)
+	if err == nil {
+		err = cal.Validate(body)
+	}
+	switch {
+	case err == nil:
+		calibrate.Status = "loaded " + path
+	case os.IsNotExist(err):
+		cal = adeept.DefaultCalibration()
+		calibrate.Status = "using defaults"
+	default:
+		cal = adeept.DefaultCalibration()
+		calibrate.Status = err.Error()
+	}
+	calibrate.Calibration = cal
+	return calibrate
+}
+
+// Direct implements DirectControl.
+func (calibrate *Calibrate) Direct() {}
+
+func (calibrate *Calibrate) Update(body *pose.Body, time, dt float32) {
+	body.Origin = g.Vec{Y: 80 * g.MM}
+	body.Orient = g.Orient{}
+
+	for i, leg := range body.Legs() {
+		for k, hinge := range leg.Hinges() {
+			hinge.Angle = 0
+			if i == calibrate.Leg && k == calibrate.Hinge {
+				hinge.Angle = calibrate.Angle
+			}
+		}
+
+		leg.IK.Target = fk.Effector(body, leg)
+		leg.IK.Planted = false
+		leg.IK.Solved = true
+		leg.IK.Debug = ""
+	}
+
+	leg := body.Legs()[calibrate.Leg]
+	if cal, ok := calibrate.Calibration.Legs[leg.Name]; ok && cal != nil {
+		hinge := cal.Hinges()[calibrate.Hinge]
+		servo := hinge.Servo()
+		leg.IK.Debug = fmt.Sprintf("%s %dµs", hingeNames[calibrate.Hinge], servo.Pulse(calibrate.Angle).Microseconds())
+	}
+}
+
+var hingeNames = [3]string{"coxa", "femur", "tibia"}
+
+// DrawPanel draws the controls for selecting and adjusting the servos.
+func (calibrate *Calibrate) DrawPanel(body *pose.Body) {
+	legs := body.Legs()
+	leg := legs[calibrate.Leg]
+	hinge := leg.Hinges()[calibrate.Hinge]
+
+	x := float32(rl.GetScreenWidth()) - 230
+	y := float32(40)
+	row := func() float32 { y += 30; return y - 30 }
+
+	raygui.Panel(rl.Rectangle{x - 10, y - 10, 230, 290}, "")
+
+	at := row()
+	if raygui.Button(rl.Rectangle{x, at, 20, 20}, "<") {
+		calibrate.move(len(legs)-1, 0)
+	}
+	raygui.Label(rl.Rectangle{x + 30, at, 150, 20}, "leg "+leg.Name)
+	if raygui.Button(rl.Rectangle{x + 190, at, 20, 20}, ">") {
+		calibrate.move(1, 0)
+	}
+
+	at = row()
+	if raygui.Button(rl.Rectangle{x, at, 20, 20}, "<") {
+		calibrate.move(0, 2)
+	}
+	raygui.Label(rl.Rectangle{x + 30, at, 150, 20}, "hinge "+hingeNames[calibrate.Hinge])
+	if raygui.Button(rl.Rectangle{x + 190, at, 20, 20}, ">") {
+		calibrate.move(0, 1)
+	}
+
+	at = row()
+	if raygui.Button(rl.Rectangle{x, at, 60, 20}, "min") {
+		calibrate.Angle = hinge.Range.Min
+	}
+	if raygui.Button(rl.Rectangle{x + 75, at, 60, 20}, "zero") {
+		calibrate.Angle = 0
+	}
+	if raygui.Button(rl.Rectangle{x + 150, at, 60, 20}, "max") {
+		calibrate.Angle = hinge.Range.Max
+	}
+
+	cal, ok := calibrate.Calibration.Legs[leg.Name]
+	if !ok || cal == nil {
+		raygui.Label(rl.Rectangle{x, row(), 210, 20}, "leg not calibrated")
+		return
+	}
+	hingecal := cal.Hinges()[calibrate.Hinge]
+
+	at = row()
+	if raygui.Button(rl.Rectangle{x, at, 40, 20}, "-1") {
+		hingecal.Trim -= 1
+	}
+	if raygui.Button(rl.Rectangle{x + 45, at, 40, 20}, "-.1") {
+		hingecal.Trim -= 0.1
+	}
+	raygui.Label(rl.Rectangle{x + 90, at, 40, 20}, fmt.Sprintf("%.1f°", hingecal.Trim))
+	if raygui.Button(rl.Rectangle{x + 125, at, 40, 20}, "+.1") {
+		hingecal.Trim += 0.1
+	}
+	if raygui.Button(rl.Rectangle{x + 170, at, 40, 20}, "+1") {
+		hingecal.Trim += 1
+	}
+
+	at = row()
+	inverted := raygui.CheckBox(rl.Rectangle{x, at, 20, 20}, "inverted", hingecal.Sign < 0)
+	if inverted {
+		hingecal.Sign = -1
+	} else {
+		hingecal.Sign = 1
+	}
+
+	servo := hingecal.Servo()
+	raygui.Label(rl.Rectangle{x, row(), 210, 20}, fmt.Sprintf("channel %d, pulse %dµs",
+		hingecal.Channel, servo.Pulse(calibrate.Angle).Microseconds()))
+
+	at = row()
+	if raygui.Button(rl.Rectangle{x, at, 100, 20}, "save") {
+		calibrate.save(body)
+	}
+	if raygui.Button(rl.Rectangle{x + 110, at, 100, 20}, "reset trim") {
+		hingecal.Trim = 0
+	}
+
+	raygui.Label(rl.Rectangle{x, row(), 210, 20}, calibrate.Status)
+}
+
+// move moves the selection forward by the given number of legs and hinges.
+func (calibrate *Calibrate) move(legs, hinges int) {
+	calibrate.Leg = (calibrate.Leg + legs) % 6
+	calibrate.Hinge = (calibrate.Hinge + hinges) % 3
+	calibrate.Angle = 0
+}
+
+// save validates and writes the calibration file.
+func (calibrate *Calibrate) save(body *pose.Body) {
+	if err := calibrate.Calibration.Validate(body); err != nil {
+		calibrate.Status = err.Error()
+		return
+	}
+	if err := adeept.SaveCalibration(calibrate.Path, calibrate.Calibration); err != nil {
+		calibrate.Status = err.Error()
+		return
+	}
+	calibrate.Status = "saved " + calibrate.Path
+}
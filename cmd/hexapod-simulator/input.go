@@ -0,0 +1,90 @@
+package main
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"github.com/egonelbre/hexapod/command"
+	"github.com/egonelbre/hexapod/g"
+)
+
+// Gamepad reads commands from the first gamepad.
+//
+// Left stick moves, right stick turns and pitches the body,
+// right trigger raises the body.
+type Gamepad struct {
+	Limits command.Limits
+}
+
+func (pad *Gamepad) Read(time, dt float32) (command.Twist, bool) {
+	if !rl.IsGamepadAvailable(0) {
+		return command.Twist{}, false
+	}
+
+	axis := func(axis int32) float32 {
+		v := rl.GetGamepadAxisMovement(0, axis)
+		if v > -0.1 && v < 0.1 {
+			return 0
+		}
+		return v
+	}
+
+	leftX, leftY := axis(rl.GamepadAxisLeftX), axis(rl.GamepadAxisLeftY)
+	rightX, rightY := axis(rl.GamepadAxisRightX), axis(rl.GamepadAxisRightY)
+	rightTrigger := axis(rl.GamepadAxisRightTrigger)
+
+	limits := &pad.Limits
+	return command.Twist{
+		Velocity: g.Vec{
+			X: limits.Velocity.Scale(-leftY),
+			Z: limits.Velocity.Scale(leftX),
+		},
+		TurnRate: limits.TurnRate * rightX,
+		Height:   limits.MinHeight + (limits.MaxHeight - limits.MinHeight).Scale(rightTrigger*0.5+0.5),
+		Orient:   g.Orient{Pitch: limits.Tilt * rightY},
+	}, true
+}
+
+// Keyboard reads commands from the keyboard.
+//
+// WASD moves, QE turns, RF changes height and arrows tilt the body.
+type Keyboard struct {
+	Limits command.Limits
+
+	Height g.Length
+	Orient g.Orient
+}
+
+func (keys *Keyboard) Read(time, dt float32) (command.Twist, bool) {
+	limits := &keys.Limits
+	if keys.Height == 0 {
+		keys.Height = limits.Height
+	}
+
+	axis := func(negative, positive int32) float32 {
+		v := float32(0)
+		if rl.IsKeyDown(negative) {
+			v--
+		}
+		if rl.IsKeyDown(positive) {
+			v++
+		}
+		return v
+	}
+
+	keys.Height += (20 * g.MM).Scale(axis(rl.KeyF, rl.KeyR) * dt)
+	keys.Height = g.Clamp(keys.Height, limits.MinHeight, limits.MaxHeight)
+	keys.Orient.Pitch += limits.Tilt * axis(rl.KeyUp, rl.KeyDown) * dt
+	keys.Orient.Roll += limits.Tilt * axis(rl.KeyLeft, rl.KeyRight) * dt
+	keys.Orient.Pitch = g.Clamp(keys.Orient.Pitch, -limits.Tilt, limits.Tilt)
+	keys.Orient.Roll = g.Clamp(keys.Orient.Roll, -limits.Tilt, limits.Tilt)
+
+	return command.Twist{
+		Velocity: g.Vec{
+			X: limits.Velocity.Scale(axis(rl.KeyS, rl.KeyW)),
+			Z: limits.Velocity.Scale(axis(rl.KeyA, rl.KeyD)),
+		},
+		TurnRate: limits.TurnRate * axis(rl.KeyQ, rl.KeyE),
+		Height:   keys.Height,
+		Orient:   keys.Orient,
+	}, true
+}
@@ -1,16 +1,35 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
 	"github.com/egonelbre/hexapod/adeept"
 	_ "github.com/egonelbre/hexapod/cmd/hexapod-simulator/internal"
 
 	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/pose"
 
 	"github.com/gen2brain/raylib-go/raygui"
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 func main() {
+	robotPath := flag.String("robot", "", "robot description file, defaults to Adeept hexapod")
+	calibration := flag.String("calibration", "../../adeept/calibration.json", "servo calibration file, only used for the Adeept hexapod")
+	flag.Parse()
+
+	body := adeept.ZeroPose()
+	if *robotPath != "" {
+		var err error
+		body, err = pose.LoadBody(*robotPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
 	const screenWidth = 1024
 	const screenHeight = 768
 
@@ -25,18 +44,24 @@ func main() {
 	camera.Up = rl.NewVector3(0.0, 1.0, 0.0)
 	camera.Fovy = 30.0
 
-	pose := adeept.ZeroPose()
-	robot := NewRobot(pose)
-	model := NewModel(pose)
-	minimap := NewMinimap(pose)
+	robot := NewRobot(body, *calibration)
+	model := NewModel(body)
+	minimap := NewMinimap(body)
 	minimap.Min = rl.Vector2{10, 100}
 	minimap.Size = rl.Vector2{200, 200}
 
 	rl.SetTargetFPS(60)
 	for !rl.WindowShouldClose() {
+		robot.Update(rl.GetFrameTime())
+
+		// follow the robot
+		origin := body.World.World(body.Origin)
+		target := rl.Vector3{X: origin.X.Meters(), Z: origin.Z.Meters()}
+		camera.Position = rl.Vector3Add(camera.Position, rl.Vector3Subtract(target, camera.Target))
+		camera.Target = target
+
 		//rl.UpdateCamera(&camera, rl.CameraFree)
 		rl.UpdateCamera(&camera, rl.CameraOrbital)
-		robot.Update(rl.GetFrameTime())
 
 		rl.BeginDrawing()
 		rl.ClearBackground(rl.RayWhite)
@@ -51,6 +76,9 @@ func main() {
 
 		DrawLabels3D(camera)
 		minimap.Draw()
+		if panel, ok := robot.Controls[robot.Active].(PanelControl); ok {
+			panel.DrawPanel(body)
+		}
 
 		rl.DrawFPS(10, 10)
 		if raygui.Button(rl.Rectangle{10, 40, 20, 20}, "<") {
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"fmt"
+
 	rl "github.com/gen2brain/raylib-go/raylib"
 
+	"github.com/egonelbre/hexapod/g"
 	"github.com/egonelbre/hexapod/pose"
+	"github.com/egonelbre/hexapod/stability"
 )
 
 type Minimap struct {
@@ -29,7 +33,10 @@ func (minimap *Minimap) Draw() {
 
 	rl.DrawRectangleV(minimap.Min, minimap.Size, rl.Fade(rl.SkyBlue, 0.5))
 
-	var bodyOrigin rl.Vector3 = body.Origin.Scale(hudScale).Meters()
+	// follow the body
+	follow := g.Vec{X: body.Origin.X, Z: body.Origin.Z}
+
+	var bodyOrigin rl.Vector3 = body.Origin.Sub(follow).Scale(hudScale).Meters()
 	var bodySize rl.Vector3 = body.Size.Scale(hudScale).Meters()
 	bodyMin := rl.Vector2Add(center, rl.Vector2{bodyOrigin.Z, -bodyOrigin.X})
 	bodyMin.X -= bodySize.Z / 2
@@ -37,8 +44,6 @@ func (minimap *Minimap) Draw() {
 
 	rl.DrawRectangleV(bodyMin, rl.Vector2{bodySize.Z, bodySize.X}, rl.DarkGray)
 
-	plantedPoints := []rl.Vector2{}
-
 	for _, leg := range body.Legs() {
 		effectorColor := EffectorColor
 		if !leg.IK.Solved {
@@ -51,12 +56,9 @@ func (minimap *Minimap) Draw() {
 		footSize := rl.Vector2{10, 10}
 
 		var p rl.Vector3
-		p = leg.IK.Target.Scale(hudScale).Meters()
+		p = leg.IK.Target.Sub(follow).Scale(hudScale).Meters()
 
 		t := rl.Vector2Add(center, rl.Vector2{p.Z, -p.X})
-		if leg.IK.Planted {
-			plantedPoints = append(plantedPoints, t)
-		}
 
 		t.X -= footSize.X / 2
 		t.Y -= footSize.Y / 2
@@ -64,11 +66,27 @@ func (minimap *Minimap) Draw() {
 		rl.DrawRectangleV(t, footSize, effectorColor)
 	}
 
-	if len(plantedPoints) >= 2 {
-		p := plantedPoints[len(plantedPoints)-1]
-		for _, n := range plantedPoints {
-			rl.DrawLineV(p, n, rl.Blue)
+	toScreen := func(v g.Vec) rl.Vector2 {
+		var p rl.Vector3 = v.Sub(follow).Scale(hudScale).Meters()
+		return rl.Vector2Add(center, rl.Vector2{p.Z, -p.X})
+	}
+
+	analysis := stability.Analyze(body)
+	supportColor := rl.Blue
+	if !analysis.Stable {
+		supportColor = rl.Red
+	}
+
+	if len(analysis.Support) >= 2 {
+		p := toScreen(analysis.Support[len(analysis.Support)-1])
+		for _, v := range analysis.Support {
+			n := toScreen(v)
+			rl.DrawLineV(p, n, supportColor)
 			p = n
 		}
 	}
+	rl.DrawCircleV(toScreen(analysis.CenterOfMass), 4, supportColor)
+
+	rl.DrawText(fmt.Sprintf("margin %.1fmm", analysis.Margin.Millimeters()),
+		int32(minimap.Min.X)+4, int32(minimap.Min.Y+minimap.Size.Y)-16, 12, supportColor)
 }
@@ -64,41 +64,51 @@ func NewModel(pose *pose.Body) *Model {
 	return model
 }
 
+// matmul composes transforms from parent to child,
+// i.e. the last matrix is applied first.
+//
+// rl.MatrixMultiply(a, b) applies a first, which is the
+// opposite of how g.Mat.Mul composes.
 func matmul(m rl.Matrix, xs ...rl.Matrix) rl.Matrix {
 	for i := range xs {
-		m = rl.MatrixMultiply(m, xs[i])
+		m = rl.MatrixMultiply(xs[i], m)
 	}
 	return m
 }
 
 func (model *Model) Draw() {
-	DrawLabel3D(".", model.Pose.Origin.Meters(), rl.Black)
+	world := model.Pose.World
+	DrawLabel3D(".", world.World(model.Pose.Origin).Meters(), rl.Black)
 
 	zero := rl.Vector3{}
 
-	bodyTransform := matmul(
+	worldTransform := matmul(
+		rl.MatrixTranslate(world.Position.XYZ()),
+		rl.MatrixRotateY(world.Yaw),
+	)
+	bodyTransform := matmul(worldTransform,
 		rl.MatrixTranslate(model.Pose.Origin.XYZ()),
 		rl.MatrixRotateY(model.Pose.Orient.Yaw),
 		rl.MatrixRotateZ(model.Pose.Orient.Pitch),
 		rl.MatrixRotateX(model.Pose.Orient.Roll),
 	)
 
-	model.Head.Transform = rl.MatrixMultiply(bodyTransform, rl.MatrixTranslate(model.Pose.Head.Offset.XYZ()))
+	model.Head.Transform = matmul(bodyTransform, rl.MatrixTranslate(model.Pose.Head.Offset.XYZ()))
 	model.Body.Transform = bodyTransform
 	rl.DrawModel(model.Head, zero, 1, HeadColor)
 	rl.DrawModel(model.Body, zero, 1, BodyColor)
 
 	zmm := (model.Pose.Size.Z/2 + model.LegPlateSize.Z/2).Meters()
 	ymm := model.Pose.Leg.RF.Offset.Y.Meters()
-	model.LegPlate.Transform = rl.MatrixMultiply(bodyTransform, rl.MatrixTranslate(0, ymm, +zmm))
+	model.LegPlate.Transform = matmul(bodyTransform, rl.MatrixTranslate(0, ymm, +zmm))
 	rl.DrawModel(model.LegPlate, zero, 1, LegPlateColor)
-	model.LegPlate.Transform = rl.MatrixMultiply(bodyTransform, rl.MatrixTranslate(0, ymm, -zmm))
+	model.LegPlate.Transform = matmul(bodyTransform, rl.MatrixTranslate(0, ymm, -zmm))
 	rl.DrawModel(model.LegPlate, zero, 1, LegPlateColor)
 
 	mm := g.MM.Meters()
 
 	for _, leg := range model.Pose.Legs() {
-		transform := rl.MatrixMultiply(bodyTransform, rl.MatrixTranslate(leg.Offset.XYZ()))
+		transform := matmul(bodyTransform, rl.MatrixTranslate(leg.Offset.XYZ()))
 
 		labelPosition := rl.Vector3Transform(rl.Vector3Zero(), transform)
 		labelPosition.Y += 30 * mm
@@ -122,27 +132,27 @@ func (model *Model) Draw() {
 			hingeCenter := rl.Vector3Transform(rl.Vector3Zero(), transform)
 			DrawLabel3D(fmt.Sprintf("%.0f", hinge.Angle*g.RadToDeg), hingeCenter, rl.Black)
 
-			hingePointMin := rl.Vector3Transform(rl.Vector3{30 * mm, 0, 0}, rl.MatrixMultiply(transform, newRotation(hinge.Zero+hinge.Range.Min)))
-			hingePointZero := rl.Vector3Transform(rl.Vector3{30 * mm, 0, 0}, rl.MatrixMultiply(transform, newRotation(hinge.Zero)))
-			hingePointMax := rl.Vector3Transform(rl.Vector3{30 * mm, 0, 0}, rl.MatrixMultiply(transform, newRotation(hinge.Zero+hinge.Range.Max)))
+			hingePointMin := rl.Vector3Transform(rl.Vector3{30 * mm, 0, 0}, matmul(transform, newRotation(hinge.Zero+hinge.Range.Min)))
+			hingePointZero := rl.Vector3Transform(rl.Vector3{30 * mm, 0, 0}, matmul(transform, newRotation(hinge.Zero)))
+			hingePointMax := rl.Vector3Transform(rl.Vector3{30 * mm, 0, 0}, matmul(transform, newRotation(hinge.Zero+hinge.Range.Max)))
 
 			rl.DrawLine3D(hingeCenter, hingePointMin, HingeMinColor)
 			rl.DrawLine3D(hingeCenter, hingePointZero, HingeZeroColor)
 			rl.DrawLine3D(hingeCenter, hingePointMax, HingeMaxColor)
 
-			transform = rl.MatrixMultiply(transform, newRotation(hinge.Zero+hinge.Angle))
-			model.Hinge.Transform = rl.MatrixMultiply(transform, hingeScale)
+			transform = matmul(transform, newRotation(hinge.Zero+hinge.Angle))
+			model.Hinge.Transform = matmul(transform, hingeScale)
 
 			hingeLength := hinge.Length.Meters()
 
-			boneTransform := rl.MatrixMultiply(transform,
+			boneTransform := matmul(transform,
 				rl.MatrixTranslate(hingeLength/2, 0, 0))
 
 			if hinge == &leg.Tibia {
-				model.Bone.Transform = rl.MatrixMultiply(boneTransform,
+				model.Bone.Transform = matmul(boneTransform,
 					rl.MatrixScale(hingeLength, 8*mm, 8*mm))
 			} else {
-				model.Bone.Transform = rl.MatrixMultiply(boneTransform,
+				model.Bone.Transform = matmul(boneTransform,
 					rl.MatrixScale(hingeLength, 12*mm, 20*mm))
 			}
 
@@ -154,20 +164,29 @@ func (model *Model) Draw() {
 
 			rl.DrawModel(model.Bone, zero, 1, BoneColor)
 
-			transform = rl.MatrixMultiply(transform, rl.MatrixTranslate(hingeLength, 0, 0))
+			linkEnd := matmul(transform, rl.MatrixTranslate(hinge.End().XYZ()))
+			if hinge.Offset != (g.Vec{}) {
+				boneEnd := rl.Vector3Transform(rl.Vector3{hingeLength, 0, 0}, transform)
+				rl.DrawLine3D(boneEnd, rl.Vector3Transform(rl.Vector3Zero(), linkEnd), BoneColor)
+			}
+			transform = linkEnd
 		}
 
 		effectorColor := EffectorColor
 		if !leg.IK.Solved {
 			effectorColor = EffectorInvalidColor
 		}
-		model.Effector.Transform = rl.MatrixMultiply(transform,
+		model.Effector.Transform = matmul(transform,
 			rl.MatrixScale(3*mm, 10*mm, 10*mm))
 		rl.DrawModel(model.Effector, zero, 1, effectorColor)
 
 		effectorWorldSpace := rl.Vector3Transform(rl.Vector3Zero(), transform)
 		effectorWorldGround := effectorWorldSpace
 		effectorWorldGround.Y = 0
+		if model.Pose.Terrain != nil {
+			x, z := g.Length(effectorWorldSpace.X)*g.M, g.Length(effectorWorldSpace.Z)*g.M
+			effectorWorldGround.Y = model.Pose.Terrain.Height(x, z).Meters()
+		}
 
 		rl.DrawLine3D(effectorWorldSpace, effectorWorldGround, effectorColor)
 
@@ -177,6 +196,6 @@ func (model *Model) Draw() {
 		}
 		rl.DrawCubeV(effectorWorldGround, rl.Vector3{plantSize, 1 * mm, plantSize}, effectorColor)
 
-		rl.DrawCubeV(leg.IK.Target.Meters(), rl.Vector3{8 * mm, 1 * mm, 8 * mm}, EffectorTargetColor)
+		rl.DrawCubeV(world.World(leg.IK.Target).Meters(), rl.Vector3{8 * mm, 1 * mm, 8 * mm}, EffectorTargetColor)
 	}
 }
@@ -2,11 +2,17 @@ package main
 
 import (
 	"fmt"
+	"math"
 
+	"github.com/egonelbre/hexapod/balance"
+	"github.com/egonelbre/hexapod/command"
 	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/gait"
 	"github.com/egonelbre/hexapod/ik/legik"
+	"github.com/egonelbre/hexapod/odometry"
 	"github.com/egonelbre/hexapod/pose"
-	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/egonelbre/hexapod/servo"
+	"github.com/egonelbre/hexapod/swing"
 )
 
 type Robot struct {
@@ -14,22 +20,45 @@ type Robot struct {
 	Time     float32
 	Active   int
 	Controls []Control
+
+	// Blend smoothly moves from the previous control after toggling.
+	Blend Blend
+	// Motion limits how fast the hinges move.
+	Motion servo.Motion
+	// Odometry tracks the robot in the world.
+	Odometry odometry.Odometry
+}
+
+// Blend is the pose at the moment of switching controls.
+type Blend struct {
+	Duration float32
+	Elapsed  float32
+
+	Origin  g.Vec
+	Orient  g.Orient
+	Targets []g.Vec
 }
 
-func NewRobot(body *pose.Body) *Robot {
+func NewRobot(body *pose.Body, calibration string) *Robot {
 	robot := &Robot{}
 	robot.Body = body
+	robot.Blend.Duration = 0.5
+	robot.Blend.Elapsed = robot.Blend.Duration
 	robot.Controls = []Control{
 		&Controller{},
-		&Stand{},
-		&Stand{},
-		&TippyTaps1{},
-		&TippyTaps2{},
-		&TippyTaps3{},
+		&Walk{},
+		&Stand{Balance: balance.NewPlanner()},
+		&Stand{Balance: balance.NewPlanner()},
+		&TippyTaps1{Balance: balance.NewPlanner()},
+		&TippyTaps2{Balance: balance.NewPlanner()},
+		&TippyTaps3{Balance: balance.NewPlanner()},
 		&Tapping{},
 		&Impatient{},
 		&Yay{},
 	}
+	if calibrate := NewCalibrate(body, calibration); calibrate != nil {
+		robot.Controls = append(robot.Controls, calibrate)
+	}
 	return robot
 }
 
@@ -44,6 +73,14 @@ func (robot *Robot) Toggle(inc int) {
 	for robot.Active >= len(robot.Controls) {
 		robot.Active -= len(robot.Controls)
 	}
+
+	robot.Blend.Elapsed = 0
+	robot.Blend.Origin = robot.Body.Origin
+	robot.Blend.Orient = robot.Body.Orient
+	robot.Blend.Targets = robot.Blend.Targets[:0]
+	for _, leg := range robot.Body.Legs() {
+		robot.Blend.Targets = append(robot.Blend.Targets, leg.IK.Target)
+	}
 }
 
 func (robot *Robot) Update(dt float32) {
@@ -51,92 +88,124 @@ func (robot *Robot) Update(dt float32) {
 
 	control := robot.Controls[robot.Active]
 	control.Update(robot.Body, robot.Time, dt)
+	if _, direct := control.(DirectControl); !direct {
+		robot.Blend.Update(robot.Body, dt)
+		legik.Solve(robot.Body)
+	}
+
+	lags := robot.Motion.Update(robot.Body, dt)
+	for _, leg := range robot.Body.Legs() {
+		for _, lag := range lags {
+			if lag.Leg == leg.Name {
+				leg.IK.Debug += " " + lag.String()
+			}
+		}
+	}
+
+	robot.Odometry.Update(odometry.Feet(robot.Body))
+	robot.Body.World = robot.Odometry.World
+}
 
-	legik.Solve(robot.Body)
+// Update moves body from the blend pose towards the current one.
+func (blend *Blend) Update(body *pose.Body, dt float32) {
+	if blend.Elapsed >= blend.Duration {
+		return
+	}
+	blend.Elapsed += dt
+
+	p := min(blend.Elapsed/blend.Duration, 1)
+	// ease in and out
+	p = p * p * (3 - 2*p)
+
+	body.Origin = blend.Origin.Lerp(body.Origin, p)
+	body.Orient.Yaw = blend.Orient.Yaw + (body.Orient.Yaw-blend.Orient.Yaw)*p
+	body.Orient.Pitch = blend.Orient.Pitch + (body.Orient.Pitch-blend.Orient.Pitch)*p
+	body.Orient.Roll = blend.Orient.Roll + (body.Orient.Roll-blend.Orient.Roll)*p
+	for i, leg := range body.Legs() {
+		leg.IK.Target = blend.Targets[i].Lerp(leg.IK.Target, p)
+	}
 }
 
 type Control interface {
 	Update(body *pose.Body, time, dt float32)
 }
 
-func ControlName(control Control) string { return fmt.Sprintf("%T", control) }
-
-type Controller struct {
-	Move g.Vec
-
-	Yaw   float32
-	Pitch float32
-	Roll  float32
-
-	Elevation g.Length
+// DirectControl sets hinge angles directly, without IK.
+type DirectControl interface {
+	Control
+	Direct()
 }
 
-func (ctrl *Controller) readInput() {
-	if rl.IsGamepadAvailable(0) {
-		leftX := rl.GetGamepadAxisMovement(0, rl.GamepadAxisLeftX)
-		leftY := rl.GetGamepadAxisMovement(0, rl.GamepadAxisLeftY)
-
-		rightX := rl.GetGamepadAxisMovement(0, rl.GamepadAxisRightX)
-		rightY := rl.GetGamepadAxisMovement(0, rl.GamepadAxisRightY)
-
-		rightTrigger := rl.GetGamepadAxisMovement(0, rl.GamepadAxisRightTrigger)
+// PanelControl draws its own user interface.
+type PanelControl interface {
+	Control
+	DrawPanel(body *pose.Body)
+}
 
-		ctrl.Move.X = g.Length(leftX * float32(g.M))
-		ctrl.Move.Y = g.Length(leftY * float32(g.M))
-		ctrl.Move = ctrl.Move.NormalizedTo(20 * g.MM)
+func ControlName(control Control) string { return fmt.Sprintf("%T", control) }
 
-		ctrl.Roll = rightX * g.Tau / 16
-		ctrl.Pitch = rightY * g.Tau / 16
+// Controller walks using gamepad or keyboard input.
+type Controller struct {
+	Walker  *command.Walker
+	Sources []command.Source
+}
 
-		ctrl.Elevation = (20 * g.MM).Scale(rightTrigger*0.5 + 0.5)
+func (ctrl *Controller) readInput(time, dt float32) command.Twist {
+	for _, source := range ctrl.Sources {
+		if twist, ok := source.Read(time, dt); ok {
+			return twist
+		}
 	}
+	return command.Twist{}
 }
 
 func (ctrl *Controller) Update(body *pose.Body, time, dt float32) {
-	ctrl.readInput()
-
-	bodyOscX := g.Sin(time * 0.5)
-	bodyOscZ := g.Cos(time + g.Tau/16)
-
-	body.Origin.X = g.Length(3*bodyOscX)*g.MM - 5*g.MM
-	body.Origin.Z = g.Length(7*bodyOscZ) * g.MM
-	body.Origin.Y = ctrl.Elevation + body.Size.Y*0.5 + 30*g.MM
-
-	body.Orient.Yaw = ctrl.Yaw
-	body.Orient.Pitch = ctrl.Pitch
-	body.Orient.Roll = ctrl.Roll
-
-	for _, leg := range body.Legs() {
-		sn, cs := g.Sincos(time + leg.Phase)
-
-		leg.IK.Target = leg.Offset.Add(leg.Offset.NormalizedTo(70 * g.MM))
-		leg.IK.Target.X = leg.Offset.X
-
-		side := g.Length(1)
-		if leg.IK.Target.Z < 0 {
-			side = -1
+	if ctrl.Walker == nil {
+		ctrl.Walker = command.NewWalker(body, gait.Tripod)
+		ctrl.Sources = []command.Source{
+			&Gamepad{Limits: ctrl.Walker.Limits},
+			&Keyboard{Limits: ctrl.Walker.Limits},
 		}
+	}
 
-		if leg.Name[1] != 'M' {
-			leg.IK.Target.Z += side * 10 * g.MM
-		} else {
-			leg.IK.Target.Z -= side * 10 * g.MM
-		}
+	ctrl.Walker.Update(body, ctrl.readInput(time, dt), dt)
+}
 
-		leg.IK.Target.Y = g.Length(20 * cs * g.MM.Float32())
-		leg.IK.Target.X += g.Length(20 * sn * g.MM.Float32())
+// Walk follows a script, while cycling through the gaits.
+type Walk struct {
+	Walker *command.Walker
+	Script *command.Script
+}
 
-		leg.IK.Planted = false
-		if leg.IK.Target.Y < 0 {
-			leg.IK.Target.Y = 0
-			leg.IK.Planted = true
+func (walk *Walk) Update(body *pose.Body, time, dt float32) {
+	if walk.Walker == nil {
+		walk.Walker = command.NewWalker(body, gait.Tripod)
+		walk.Walker.Acceleration = 20 * g.MM
+		walk.Walker.TurnAcceleration = g.Tau / 32
+		walk.Script = &command.Script{
+			Loop: true,
+			Steps: []command.Step{
+				{Duration: 6, Twist: command.Twist{Velocity: g.Vec{X: 20 * g.MM}}},
+				{Duration: 4, Twist: command.Twist{Velocity: g.Vec{X: 15 * g.MM}, TurnRate: g.Tau / 32}},
+				{Duration: 4, Twist: command.Twist{Velocity: g.Vec{Z: 15 * g.MM}, Height: 35 * g.MM}},
+				{Duration: 4, Twist: command.Crab(15*g.MM, -3*g.Tau/8)},
+				{Duration: 4, Twist: command.TurnInPlace(-g.Tau/16, g.Vec{})},
+				{Duration: 6, Twist: command.TurnInPlace(g.Tau/16, g.Vec{Z: -150 * g.MM})},
+			},
 		}
 	}
+
+	walk.Walker.Engine.Gait = gait.Gaits[int(time/10)%len(gait.Gaits)]
+
+	twist, _ := walk.Script.Read(time, dt)
+	walk.Walker.Update(body, twist, dt)
 }
 
-type Stand struct{}
+type Stand struct {
+	Balance *balance.Planner
+}
 
-func (*Stand) Update(body *pose.Body, time, dt float32) {
+func (stand *Stand) Update(body *pose.Body, time, dt float32) {
 	bodyOscY := g.Sin(time)
 	if bodyOscY < 0 {
 		bodyOscY = -bodyOscY
@@ -146,59 +215,52 @@ func (*Stand) Update(body *pose.Body, time, dt float32) {
 	body.Orient.Pitch = g.Sin(time*0.1) * g.Tau / 32
 	body.Orient.Yaw = g.Sin(time*0.14) * g.Tau / 32
 	body.Orient.Roll = g.Sin(time*0.20) * g.Tau / 32
-
-	bodyOscX := g.Sin(time * 0.5)
-	bodyOscZ := g.Cos(time + g.Tau/16)
-	body.Origin.X = g.Length(3*bodyOscX)*g.MM - 5*g.MM
-	body.Origin.Z = g.Length(7*bodyOscZ) * g.MM
+	body.Origin.X, body.Origin.Z = 0, 0
 
 	for _, leg := range body.Legs() {
 		leg.IK.Target = leg.Offset.Add(leg.Offset.NormalizedTo(70 * g.MM))
 		leg.IK.Target.Y = 0
 		leg.IK.Planted = true
 	}
+
+	stand.Balance.Update(body, nil, dt)
 }
 
-type TippyTaps1 struct{}
+type TippyTaps1 struct {
+	Balance *balance.Planner
+}
 
-func (*TippyTaps1) Update(body *pose.Body, time, dt float32) {
+func (taps *TippyTaps1) Update(body *pose.Body, time, dt float32) {
 	body.Origin.Y = body.Size.Y/2 + body.Size.Y/4
 
 	bodyOscY := g.Sin(time * 2)
 	body.Origin.Y = g.Length(bodyOscY*float32(5*g.MM)) + body.Size.Y
 
-	bodyOscX := g.Sin(time * 0.5)
-	bodyOscZ := g.Cos(time + g.Tau/16)
-	body.Origin.X = g.Length(3*bodyOscX)*g.MM - 5*g.MM
-	body.Origin.Z = g.Length(5*bodyOscZ) * g.MM
+	body.Origin.X, body.Origin.Z = 0, 0
+	body.Orient = g.Orient{}
 
+	var lifts []balance.Lift
 	for _, leg := range body.Legs() {
-		_, cs := g.Sincos(time + leg.Phase)
-
-		leg.IK.Target = leg.Offset.Add(leg.Offset.NormalizedTo(70 * g.MM))
-		leg.IK.Target.Y = g.Length(20 * cs * g.MM.Float32())
-
-		leg.IK.Planted = false
-		if leg.IK.Target.Y < 0 {
-			leg.IK.Target.Y = 0
-			leg.IK.Planted = true
-		}
+		lifts = append(lifts, tap(leg, time+leg.Phase, 1))
 	}
+
+	taps.Balance.Update(body, lifts, dt)
 }
 
-type TippyTaps2 struct{}
+type TippyTaps2 struct {
+	Balance *balance.Planner
+}
 
-func (*TippyTaps2) Update(body *pose.Body, time, dt float32) {
+func (taps *TippyTaps2) Update(body *pose.Body, time, dt float32) {
 	body.Origin.Y = body.Size.Y/2 + body.Size.Y/4
 
 	bodyOscY := g.Sin(time * 2)
 	body.Origin.Y = g.Length(bodyOscY*float32(5*g.MM)) + body.Size.Y
 
-	bodyOscX := g.Sin(time * 0.5)
-	bodyOscZ := g.Cos(time + g.Tau/16)
-	body.Origin.X = g.Length(3*bodyOscX)*g.MM - 5*g.MM
-	body.Origin.Z = g.Length(10*bodyOscZ) * g.MM
+	body.Origin.X, body.Origin.Z = 0, 0
+	body.Orient = g.Orient{}
 
+	var lifts []balance.Lift
 	for _, leg := range body.Legs() {
 		if leg.Name == "RB" || leg.Name == "LB" {
 			leg.IK.Target = leg.Offset.Add(leg.Offset.NormalizedTo(70 * g.MM))
@@ -207,22 +269,17 @@ func (*TippyTaps2) Update(body *pose.Body, time, dt float32) {
 			continue
 		}
 
-		_, cs := g.Sincos(time + leg.Phase)
-
-		leg.IK.Target = leg.Offset.Add(leg.Offset.NormalizedTo(70 * g.MM))
-		leg.IK.Target.Y = g.Length(20 * cs * g.MM.Float32())
-
-		leg.IK.Planted = false
-		if leg.IK.Target.Y < 0 {
-			leg.IK.Target.Y = 0
-			leg.IK.Planted = true
-		}
+		lifts = append(lifts, tap(leg, time+leg.Phase, 1))
 	}
+
+	taps.Balance.Update(body, lifts, dt)
 }
 
-type TippyTaps3 struct{}
+type TippyTaps3 struct {
+	Balance *balance.Planner
+}
 
-func (*TippyTaps3) Update(body *pose.Body, time, dt float32) {
+func (taps *TippyTaps3) Update(body *pose.Body, time, dt float32) {
 	body.Origin.Y = body.Size.Y/2 + body.Size.Y/4
 
 	bodyOscY := g.Sin(time)
@@ -231,11 +288,10 @@ func (*TippyTaps3) Update(body *pose.Body, time, dt float32) {
 	}
 	body.Origin.Y = g.Length(bodyOscY*float32(10*g.MM)) + body.Size.Y
 
-	bodyOscX := g.Sin(time * 0.5)
-	bodyOscZ := g.Cos(time + g.Tau/16)
-	body.Origin.X = g.Length(3*bodyOscX)*g.MM - 5*g.MM
-	body.Origin.Z = g.Length(10*bodyOscZ) * g.MM
+	body.Origin.X, body.Origin.Z = 0, 0
+	body.Orient = g.Orient{}
 
+	var lifts []balance.Lift
 	for _, leg := range body.Legs() {
 		if leg.Name == "RB" || leg.Name == "LB" {
 			leg.IK.Target = leg.Offset.Add(leg.Offset.NormalizedTo(70 * g.MM))
@@ -251,18 +307,32 @@ func (*TippyTaps3) Update(body *pose.Body, time, dt float32) {
 			phase = 0
 		}
 
-		sn, cs := g.Sincos(time + phase)
+		lifts = append(lifts, tap(leg, time+phase, 1))
+	}
 
-		leg.IK.Target = leg.Offset.Add(leg.Offset.NormalizedTo(70 * g.MM))
-		leg.IK.Target.Y = g.Length(20 * cs * g.MM.Float32())
-		_ = sn
-		//leg.IK.Target.X += g.Length(20 * sn * g.MM.Float32())
-		leg.IK.Planted = false
-		if leg.IK.Target.Y < 0 {
-			leg.IK.Target.Y = 0
-			leg.IK.Planted = true
-		}
+	taps.Balance.Update(body, lifts, dt)
+}
+
+// tapSwing is the foot trajectory when tapping in place.
+var tapSwing = swing.Shape{Curve: swing.MinimumJerk, Height: 20 * g.MM}
+
+// tap lifts the foot at home position while cos(angle) is positive,
+// where angle changes by rate per second. It returns when the leg lifts next.
+func tap(leg *pose.Leg, angle, rate g.Radians) balance.Lift {
+	home := gait.Home(leg)
+
+	s := float32(math.Mod(float64((angle+g.Tau/4)/(g.Tau/2)), 2))
+	if s < 0 {
+		s += 2
 	}
+
+	leg.IK.Target, leg.IK.Planted = home, true
+	if s < 1 {
+		traj := tapSwing.Between(home, home, 0)
+		leg.IK.Target, leg.IK.Planted = traj.Sample(s, home.Y)
+		return balance.Lift{Leg: leg}
+	}
+	return balance.Lift{Leg: leg, In: (2 - s) * g.Tau / 2 / rate}
 }
 
 type Tapping struct{}
@@ -282,17 +352,7 @@ func (*Tapping) Update(body *pose.Body, time, dt float32) {
 			continue
 		}
 
-		_, cs := g.Sincos(time*2 + g.Tau/2)
-
-		leg.IK.Target = leg.Offset.Add(leg.Offset.NormalizedTo(70 * g.MM))
-		leg.IK.Target.Y = g.Length(20 * cs * g.MM.Float32())
-
-		//leg.IK.Target.X += g.Length(20 * sn * g.MM.Float32())
-		leg.IK.Planted = false
-		if leg.IK.Target.Y < 0 {
-			leg.IK.Target.Y = 0
-			leg.IK.Planted = true
-		}
+		tap(leg, time*2+g.Tau/2, 0.2*2)
 	}
 }
 
@@ -316,17 +376,7 @@ func (*Impatient) Update(body *pose.Body, time, dt float32) {
 			continue
 		}
 
-		_, cs := g.Sincos(time*6 + g.Tau/2)
-
-		leg.IK.Target = leg.Offset.Add(leg.Offset.NormalizedTo(70 * g.MM))
-		leg.IK.Target.Y = g.Length(20 * cs * g.MM.Float32())
-
-		//leg.IK.Target.X += g.Length(20 * sn * g.MM.Float32())
-		leg.IK.Planted = false
-		if leg.IK.Target.Y < 0 {
-			leg.IK.Target.Y = 0
-			leg.IK.Planted = true
-		}
+		tap(leg, time*6+g.Tau/2, 0.2*6)
 	}
 }
 
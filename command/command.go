@@ -0,0 +1,98 @@
+// package command describes the desired motion of the robot
+package command
+
+import (
+	"github.com/egonelbre/hexapod/g"
+)
+
+// Twist is the desired motion and posture of the body.
+type Twist struct {
+	// Velocity is the linear velocity in body ground space, per second.
+	// Y is ignored.
+	Velocity g.Vec
+	// TurnRate is the angular velocity around Y, per second.
+	TurnRate g.Radians
+	// Center is the point the body turns around, in body ground space.
+	Center g.Vec
+	// Height is the body height above the ground, zero for the default.
+	Height g.Length
+	// Orient is the orientation of the body relative to its heading.
+	Orient g.Orient
+}
+
+// TurnInPlace turns around center at rate per second.
+func TurnInPlace(rate g.Radians, center g.Vec) Twist {
+	return Twist{TurnRate: rate, Center: center}
+}
+
+// Crab walks in direction, measured clockwise from forward, without turning.
+func Crab(speed g.Length, direction g.Radians) Twist {
+	return Twist{Velocity: g.RotateY(direction).Transform(g.Vec{X: speed})}
+}
+
+// BodyVelocity returns the velocity of the body origin, including the
+// motion from turning around Center.
+func (twist *Twist) BodyVelocity() g.Vec {
+	// the origin circles around the center
+	return g.Vec{
+		X: twist.Velocity.X + twist.Center.Z.Scale(twist.TurnRate),
+		Z: twist.Velocity.Z - twist.Center.X.Scale(twist.TurnRate),
+	}
+}
+
+// Source provides commands, e.g. a gamepad, keyboard, network or a script.
+type Source interface {
+	// Read returns the current command and whether the source is active.
+	Read(time, dt float32) (Twist, bool)
+}
+
+// Limits restricts the commands.
+type Limits struct {
+	Velocity g.Length  // per second
+	TurnRate g.Radians // per second
+
+	Height    g.Length // default
+	MinHeight g.Length
+	MaxHeight g.Length
+
+	// Tilt is the maximum yaw, pitch and roll.
+	Tilt g.Radians
+}
+
+// DefaultLimits are suitable for the Adeept hexapod.
+var DefaultLimits = Limits{
+	Velocity:  40 * g.MM,
+	TurnRate:  g.Tau / 8,
+	Height:    45 * g.MM,
+	MinHeight: 25 * g.MM,
+	MaxHeight: 70 * g.MM,
+	Tilt:      g.Tau / 16,
+}
+
+// Clamp restricts the command to limits.
+//
+// Velocity and TurnRate are scaled together to keep the turn center.
+func (limits *Limits) Clamp(twist Twist) Twist {
+	twist.Velocity.Y = 0
+	twist.Center.Y = 0
+
+	scale := float32(1)
+	if speed := twist.BodyVelocity().Length(); speed > limits.Velocity {
+		scale = (limits.Velocity / speed).Float32()
+	}
+	if turn := g.Abs(twist.TurnRate) * scale; turn > limits.TurnRate {
+		scale *= limits.TurnRate / turn
+	}
+	twist.Velocity = twist.Velocity.Scale(scale)
+	twist.TurnRate *= scale
+
+	if twist.Height == 0 {
+		twist.Height = limits.Height
+	}
+	twist.Height = min(max(twist.Height, limits.MinHeight), limits.MaxHeight)
+
+	twist.Orient.Yaw = g.Clamp(twist.Orient.Yaw, -limits.Tilt, limits.Tilt)
+	twist.Orient.Pitch = g.Clamp(twist.Orient.Pitch, -limits.Tilt, limits.Tilt)
+	twist.Orient.Roll = g.Clamp(twist.Orient.Roll, -limits.Tilt, limits.Tilt)
+	return twist
+}
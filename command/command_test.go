@@ -0,0 +1,167 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/command"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/gait"
+	"github.com/egonelbre/hexapod/odometry"
+	"github.com/egonelbre/hexapod/pose"
+	"github.com/egonelbre/hexapod/terrain"
+)
+
+// targets returns leg IK targets as feet.
+func targets(body *pose.Body) []odometry.Foot {
+	var feet []odometry.Foot
+	for _, leg := range body.Legs() {
+		feet = append(feet, odometry.Foot{Position: leg.IK.Target, Planted: leg.IK.Planted})
+	}
+	return feet
+}
+
+func TestClamp(t *testing.T) {
+	limits := command.DefaultLimits
+	got := limits.Clamp(command.Twist{
+		Velocity: g.Vec{X: 300 * g.MM, Y: 10 * g.MM, Z: 400 * g.MM},
+		TurnRate: -g.Tau,
+		Orient:   g.Orient{Pitch: g.Tau / 4, Roll: -g.Tau / 64},
+	})
+
+	exp := command.Twist{
+		Velocity: g.Vec{X: 24 * g.MM, Z: 32 * g.MM},
+		TurnRate: -g.Tau * 0.08, // scaled with the velocity
+		Height:   limits.Height,
+		Orient:   g.Orient{Pitch: limits.Tilt, Roll: -g.Tau / 64},
+	}
+	if got.Velocity.Distance(exp.Velocity) > 0.01*g.MM || g.Abs(got.TurnRate-exp.TurnRate) > 1e-6 ||
+		got.Height != exp.Height || got.Orient != exp.Orient {
+		t.Errorf("got %v; exp %v", got, exp)
+	}
+
+	if got := limits.Clamp(command.Twist{Height: g.M}); got.Height != limits.MaxHeight {
+		t.Errorf("height got %v; exp %v", got.Height, limits.MaxHeight)
+	}
+}
+
+func TestScript(t *testing.T) {
+	forward := command.Twist{Velocity: g.Vec{X: 10 * g.MM}}
+	turn := command.Twist{TurnRate: g.Tau / 16}
+	script := &command.Script{Steps: []command.Step{
+		{Duration: 1, Twist: forward},
+		{Duration: 2, Twist: turn},
+	}}
+
+	type Case struct {
+		Time   float32
+		Twist  command.Twist
+		Active bool
+	}
+	cases := []Case{
+		{0.5, forward, true},
+		{1.5, turn, true},
+		{3.5, command.Twist{}, false},
+	}
+	for _, c := range cases {
+		if twist, active := script.Read(c.Time, 0); twist != c.Twist || active != c.Active {
+			t.Errorf("%v: got %v %v; exp %v %v", c.Time, twist, active, c.Twist, c.Active)
+		}
+	}
+
+	script.Loop = true
+	loops := []Case{
+		{3.5, forward, true},
+		{3e6 + 1.5, turn, true},
+		{-0.5, turn, true},
+		{-2.5, forward, true},
+	}
+	for _, c := range loops {
+		if twist, active := script.Read(c.Time, 0); twist != c.Twist || active != c.Active {
+			t.Errorf("loop %v: got %v %v; exp %v %v", c.Time, twist, active, c.Twist, c.Active)
+		}
+	}
+}
+
+func TestWalker(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Terrain = terrain.Flat{Level: 20 * g.MM}
+	walker := command.NewWalker(body, gait.Tripod)
+	twist := command.Twist{
+		Velocity: g.Vec{X: 30 * g.MM},
+		Height:   40 * g.MM,
+		Orient:   g.Orient{Pitch: g.Tau / 64},
+	}
+
+	var odo odometry.Odometry
+
+	const dt = 1.0 / 60
+	for frame := 0; frame < 120; frame++ {
+		walker.Update(body, twist, dt)
+		odo.Update(targets(body))
+	}
+
+	if body.Origin != (g.Vec{Y: twist.Height + 20*g.MM}) || body.Orient != twist.Orient {
+		t.Errorf("body got %v %v", body.Origin, body.Orient)
+	}
+	if walker.Engine.CurrentVelocity != twist.Velocity {
+		t.Errorf("velocity got %v; exp %v", walker.Engine.CurrentVelocity, twist.Velocity)
+	}
+	if odo.World.Position.X < 40*g.MM {
+		t.Errorf("body did not move forward %v", odo.World)
+	}
+}
+
+func TestWalkerWorld(t *testing.T) {
+	type Case struct {
+		Name  string
+		Twist command.Twist
+	}
+	cases := []Case{
+		{"crab", command.Crab(30*g.MM, g.Tau/3)},
+		{"turn", command.TurnInPlace(g.Tau/8, g.Vec{})},
+		{"around", command.TurnInPlace(g.Tau/16, g.Vec{X: -50 * g.MM, Z: 150 * g.MM})},
+		{"curve", command.Twist{Velocity: g.Vec{X: 20 * g.MM, Z: 10 * g.MM}, TurnRate: -g.Tau / 16}},
+	}
+
+	for _, c := range cases {
+		body := adeept.ZeroPose()
+		walker := command.NewWalker(body, gait.Ripple)
+		var odo odometry.Odometry
+		odo.Reset(pose.Ground{Position: g.Vec{X: 100 * g.MM, Z: -30 * g.MM}, Yaw: g.Tau / 5})
+		center := odo.World.World(c.Twist.Center)
+
+		const dt = 1.0 / 60
+		walker.Update(body, c.Twist, dt)
+		odo.Update(targets(body))
+
+		previous := map[string]g.Vec{}
+		for _, leg := range body.Legs() {
+			previous[leg.Name] = odo.World.World(leg.IK.Target)
+		}
+		for frame := 0; frame < 300; frame++ {
+			planted := map[string]bool{}
+			for _, leg := range body.Legs() {
+				planted[leg.Name] = leg.IK.Planted
+			}
+
+			walker.Update(body, c.Twist, dt)
+			odo.Update(targets(body))
+			for _, leg := range body.Legs() {
+				current := odo.World.World(leg.IK.Target)
+				if planted[leg.Name] && leg.IK.Planted {
+					if d := current.Distance(previous[leg.Name]); d > 0.1*g.MM {
+						t.Errorf("%s: frame %d, %s slipped %.3fmm", c.Name, frame, leg.Name, d.Millimeters())
+					}
+				}
+				previous[leg.Name] = current
+			}
+		}
+
+		if c.Twist.Velocity == (g.Vec{}) {
+			if d := odo.World.World(c.Twist.Center).Distance(center); d > 0.5*g.MM {
+				t.Errorf("%s: turn center moved %.3fmm", c.Name, d.Millimeters())
+			}
+		}
+	}
+}
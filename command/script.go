@@ -0,0 +1,45 @@
+package command
+
+import "math"
+
+// Script is a sequence of commands.
+type Script struct {
+	Steps []Step
+	// Loop restarts the script after the last step.
+	Loop bool
+}
+
+// Step holds a command for a duration in seconds.
+type Step struct {
+	Duration float32
+	Twist    Twist
+}
+
+// Read returns the command at time, the script is inactive after the last step.
+// With Loop any time, including negative, is wrapped into the script.
+func (script *Script) Read(time, dt float32) (Twist, bool) {
+	var total float32
+	for _, step := range script.Steps {
+		total += step.Duration
+	}
+	if total <= 0 {
+		return Twist{}, false
+	}
+
+	if script.Loop {
+		time = float32(math.Mod(float64(time), float64(total)))
+		if time < 0 {
+			time += total
+		}
+		if time >= total {
+			time = 0
+		}
+	}
+	for _, step := range script.Steps {
+		if time < step.Duration {
+			return step.Twist, true
+		}
+		time -= step.Duration
+	}
+	return Twist{}, false
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/gait"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+// Walker moves the body and feet according to commands.
+//
+// Body and feet are moved in body ground space, the world pose of the body
+// is tracked separately, e.g. by odometry.
+type Walker struct {
+	Engine *gait.Engine
+	Limits Limits
+
+	// Acceleration limits the change of velocity, per second².
+	Acceleration g.Length
+	// TurnAcceleration limits the change of turn rate, per second².
+	TurnAcceleration g.Radians
+}
+
+// NewWalker creates a walker using the gait.
+func NewWalker(body *pose.Body, pattern gait.Gait) *Walker {
+	return &Walker{
+		Engine:           gait.NewEngine(body, pattern),
+		Limits:           DefaultLimits,
+		Acceleration:     100 * g.MM,
+		TurnAcceleration: g.Tau / 4,
+	}
+}
+
+// Update applies the command to the body and advances the gait by dt seconds.
+func (walker *Walker) Update(body *pose.Body, twist Twist, dt float32) {
+	twist = walker.Limits.Clamp(twist)
+
+	engine := walker.Engine
+	engine.Velocity = twist.BodyVelocity()
+	engine.TurnRate = twist.TurnRate
+	engine.Acceleration = walker.Acceleration
+	engine.TurnAcceleration = walker.TurnAcceleration
+	engine.Update(body, dt)
+
+	// keep the body level, at height over the terrain under the feet
+	body.Origin = g.Vec{Y: twist.Height + walker.groundHeight(body)}
+	body.Orient = twist.Orient
+}
+
+// groundHeight returns the average terrain height under the home positions
+// of the feet.
+func (walker *Walker) groundHeight(body *pose.Body) g.Length {
+	ground := body.Ground()
+
+	var total g.Length
+	for _, leg := range walker.Engine.Legs {
+		total += ground.Height(leg.Home.X, leg.Home.Z)
+	}
+	return total / g.Length(len(walker.Engine.Legs))
+}
@@ -0,0 +1,110 @@
+// package fk calculates forward kinematics for a pose.Body
+package fk
+
+import (
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+// Body contains body ground space positions of the body.
+type Body struct {
+	Origin g.Vec
+	Head   g.Vec
+	Legs   []Leg // in the same order as pose.Body.Legs()
+}
+
+// Leg contains body ground space positions of a leg.
+type Leg struct {
+	Leg *pose.Leg
+
+	Joints   []g.Vec // position of each hinge in pose.Leg.Hinges()
+	Frames   []g.Mat // transform of each hinge after rotation, X points along the link
+	Effector g.Vec
+}
+
+// Solve calculates body ground space positions for the whole body.
+//
+// Use body.World to convert them to world space.
+func Solve(body *pose.Body) Body {
+	bodySpace := BodySpace(body)
+
+	result := Body{
+		Origin: body.Origin,
+		Head:   bodySpace.Transform(body.Head.Offset),
+	}
+	for _, leg := range body.Legs() {
+		result.Legs = append(result.Legs, SolveLeg(body, leg))
+	}
+	return result
+}
+
+// SolveLeg calculates body ground space positions of leg joints.
+func SolveLeg(body *pose.Body, leg *pose.Leg) Leg {
+	hinges := leg.Hinges()
+	result := Leg{
+		Leg:    leg,
+		Joints: make([]g.Vec, 0, len(hinges)),
+		Frames: make([]g.Mat, 0, len(hinges)),
+	}
+
+	transform := LegSpace(body, leg)
+	for _, hinge := range hinges {
+		result.Joints = append(result.Joints, transform.Transform(g.Vec{}))
+		transform = transform.Mul(Rotate(hinge.Axis, hinge.Zero+hinge.Angle))
+		result.Frames = append(result.Frames, transform)
+		transform = transform.Mul(Translate(hinge.End()))
+	}
+	result.Effector = transform.Transform(g.Vec{})
+
+	return result
+}
+
+// Effector calculates body ground space position of the leg end effector.
+func Effector(body *pose.Body, leg *pose.Leg) g.Vec {
+	return Chain(LegSpace(body, leg), leg.Hinges())
+}
+
+// Chain calculates the position of the end of hinges starting at root,
+// in the space that root transforms to.
+func Chain(root g.Mat, hinges []*pose.Hinge) g.Vec {
+	transform := root
+	for _, hinge := range hinges {
+		transform = transform.Mul(Link(hinge))
+	}
+	return transform.Transform(g.Vec{})
+}
+
+// BodySpace returns the transform from body space to body ground space.
+func BodySpace(body *pose.Body) g.Mat {
+	return g.Translate(body.Origin.X, body.Origin.Y, body.Origin.Z).
+		Mul(body.Orient.Mat())
+}
+
+// LegSpace returns the transform from leg space to body ground space.
+//
+// Leg space has the origin at the Coxa hinge and axes aligned with the body.
+func LegSpace(body *pose.Body, leg *pose.Leg) g.Mat {
+	return BodySpace(body).Mul(Translate(leg.Offset))
+}
+
+// Link returns the transform from the end of the hinge link to the hinge root.
+func Link(hinge *pose.Hinge) g.Mat {
+	return Rotate(hinge.Axis, hinge.Zero+hinge.Angle).
+		Mul(Translate(hinge.End()))
+}
+
+// Translate returns translation by v.
+func Translate(v g.Vec) g.Mat { return g.Translate(v.X, v.Y, v.Z) }
+
+// Rotate returns rotation around the axis.
+func Rotate(axis pose.Axis, angle g.Radians) g.Mat {
+	switch axis {
+	case pose.X:
+		return g.RotateX(angle)
+	case pose.Y:
+		return g.RotateY(angle)
+	case pose.Z:
+		return g.RotateZ(angle)
+	}
+	panic("invalid axis")
+}
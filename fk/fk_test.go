@@ -0,0 +1,100 @@
+package fk_test
+
+import (
+	"testing"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/fk"
+	"github.com/egonelbre/hexapod/g"
+)
+
+func approxEqualVec(a, b g.Vec) bool {
+	return a.Sub(b).Length() < 0.1*g.MM
+}
+
+func TestSolveLeg(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Origin = g.Vec{X: 10 * g.MM, Y: 50 * g.MM, Z: 20 * g.MM}
+	leg := &body.Leg.RM
+
+	root := body.Origin.Add(leg.Offset)
+	coxa := leg.Coxa.Length
+	femur := leg.Femur.Length
+	tibia := leg.Tibia.Length
+
+	type Case struct {
+		Orient g.Orient
+		Angles [3]g.Radians
+		Joints [3]g.Vec
+		Effect g.Vec
+	}
+	cases := []Case{
+		{ // straight leg pointing right
+			Angles: [3]g.Radians{0, 0, 0},
+			Joints: [3]g.Vec{
+				root,
+				root.Add(g.Vec{Z: coxa}),
+				root.Add(g.Vec{Z: coxa + femur}),
+			},
+			Effect: root.Add(g.Vec{Z: coxa + femur + tibia}),
+		},
+		{ // coxa turned forward, femur horizontal, tibia down
+			Angles: [3]g.Radians{-g.Tau / 4, 0, g.Tau / 4},
+			Joints: [3]g.Vec{
+				root,
+				root.Add(g.Vec{X: coxa}),
+				root.Add(g.Vec{X: coxa + femur}),
+			},
+			Effect: root.Add(g.Vec{X: coxa + femur, Y: -tibia}),
+		},
+		{ // body turned right by quarter turn, leg points back
+			Orient: g.Orient{Yaw: g.Tau / 4},
+			Angles: [3]g.Radians{0, g.Tau / 4, 0},
+			Joints: [3]g.Vec{
+				body.Origin.Add(g.Vec{X: -leg.Offset.Z, Y: leg.Offset.Y, Z: leg.Offset.X}),
+				body.Origin.Add(g.Vec{X: -leg.Offset.Z - coxa, Y: leg.Offset.Y, Z: leg.Offset.X}),
+				body.Origin.Add(g.Vec{X: -leg.Offset.Z - coxa, Y: leg.Offset.Y - femur, Z: leg.Offset.X}),
+			},
+			Effect: body.Origin.Add(g.Vec{X: -leg.Offset.Z - coxa, Y: leg.Offset.Y - femur - tibia, Z: leg.Offset.X}),
+		},
+	}
+
+	for i, c := range cases {
+		body.Orient = c.Orient
+		leg.Coxa.Angle, leg.Femur.Angle, leg.Tibia.Angle = c.Angles[0], c.Angles[1], c.Angles[2]
+
+		r := fk.SolveLeg(body, leg)
+		for k, joint := range r.Joints {
+			if !approxEqualVec(joint, c.Joints[k]) {
+				t.Errorf("%d: joint %d got %v; exp %v", i, k, joint, c.Joints[k])
+			}
+		}
+		if !approxEqualVec(r.Effector, c.Effect) {
+			t.Errorf("%d: effector got %v; exp %v", i, r.Effector, c.Effect)
+		}
+		if e := fk.Effector(body, leg); !approxEqualVec(e, r.Effector) {
+			t.Errorf("%d: Effector got %v; exp %v", i, e, r.Effector)
+		}
+	}
+}
+
+func TestSolve(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Orient = g.Orient{Yaw: 0.3, Pitch: -0.2, Roll: 0.1}
+
+	r := fk.Solve(body)
+	legs := body.Legs()
+	if len(r.Legs) != len(legs) {
+		t.Fatalf("got %d legs; exp %d", len(r.Legs), len(legs))
+	}
+	for i, leg := range legs {
+		if r.Legs[i].Leg != leg {
+			t.Errorf("%d: got leg %q; exp %q", i, r.Legs[i].Leg.Name, leg.Name)
+		}
+	}
+
+	head := body.Orient.Mat().Transform(body.Head.Offset).Add(body.Origin)
+	if !approxEqualVec(r.Head, head) {
+		t.Errorf("head got %v; exp %v", r.Head, head)
+	}
+}
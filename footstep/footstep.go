@@ -0,0 +1,266 @@
+// package footstep plans footholds for walking to a goal pose
+package footstep
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/gait"
+	"github.com/egonelbre/hexapod/ik/workspace"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+// Step moves a group of legs to new footholds.
+type Step struct {
+	// LiftOff and TouchDown are times from the start of the plan, in cycles.
+	LiftOff, TouchDown float32
+	// Legs are the moved legs as indices of pose.Body.Legs().
+	Legs []int
+	// Footholds are world space targets of Legs.
+	Footholds []g.Vec
+	// Body is the body pose at touch-down.
+	Body pose.Ground
+}
+
+// Plan is a sequence of steps from Start to Goal.
+type Plan struct {
+	Start, Goal pose.Ground
+	// Cycles is the number of step cycles the body moves.
+	Cycles int
+	Steps  []Step
+}
+
+// At returns the body pose at time t, in cycles.
+func (plan *Plan) At(t float32) pose.Ground {
+	if plan.Cycles == 0 {
+		return plan.Goal
+	}
+	return plan.Start.Lerp(plan.Goal, min(max(t/float32(plan.Cycles), 0), 1))
+}
+
+// Velocity returns the body velocity and turn rate in body ground space
+// at time t in cycles, where period is the cycle duration in seconds.
+//
+// The result can be used as gait.Engine Velocity and TurnRate.
+func (plan *Plan) Velocity(t, period float32) (g.Vec, g.Radians) {
+	if t < 0 || t >= float32(plan.Cycles) {
+		return g.Vec{}, 0
+	}
+
+	duration := float32(plan.Cycles) * period
+	velocity := plan.Goal.Position.Sub(plan.Start.Position).Scale(1 / duration)
+	velocity.Y = 0
+	velocity = g.RotateY(-plan.At(t).Yaw).Transform(velocity)
+	return velocity, pose.Turn(plan.Start.Yaw, plan.Goal.Yaw) / duration
+}
+
+// TouchDown implements gait.Footholds for following the plan, where
+// the engine Time is the plan time and the body moves with Velocity.
+//
+// The step is found by the lift-off time, hence a leg that lifts off
+// late still lands on its foothold.
+func (plan *Plan) TouchDown(leg int, liftOff, now float32) (g.Vec, bool) {
+	for _, step := range plan.Steps {
+		if delta := step.LiftOff - liftOff; delta <= -0.5 || delta >= 0.5 {
+			continue
+		}
+		for k, i := range step.Legs {
+			if i == leg {
+				return plan.At(now).Local(step.Footholds[k]), true
+			}
+		}
+	}
+	return g.Vec{}, false
+}
+
+// ErrHomeUnreachable is returned when a leg cannot stand at its home position.
+var ErrHomeUnreachable = errors.New("footstep: home position unreachable")
+
+// Planner plans footholds within the leg workspaces.
+type Planner struct {
+	Gait gait.Gait
+	// Margin is the minimum distance of footholds from the workspace edges.
+	Margin g.Length
+	// MaxCycles limits the length of the plan.
+	MaxCycles int
+
+	// Homes and Footprints are in body ground space, in the same order
+	// as pose.Body.Legs().
+	Homes      []g.Vec
+	Footprints []g.Polygon
+}
+
+// NewPlanner creates a planner for the body at the current height and tilt.
+func NewPlanner(body *pose.Body, pattern gait.Gait) (*Planner, error) {
+	planner := &Planner{
+		Gait:      pattern,
+		Margin:    5 * g.MM,
+		MaxCycles: 1000,
+	}
+
+	neutral := *body
+	neutral.Origin.X, neutral.Origin.Z = 0, 0
+	neutral.Orient.Yaw = 0
+
+	for _, leg := range neutral.Legs() {
+		home := gait.Home(leg)
+		footprint := workspace.Footprint(&neutral, leg, 0, 24)
+		if !planner.inside(footprint, home) {
+			return nil, fmt.Errorf("%w: %s", ErrHomeUnreachable, leg.Name)
+		}
+		planner.Homes = append(planner.Homes, home)
+		planner.Footprints = append(planner.Footprints, footprint)
+	}
+
+	return planner, nil
+}
+
+// Plan finds the shortest plan where all the feet stay within their workspace.
+//
+// The number of cycles is doubled until the plan is feasible and then
+// bisected, assuming that slower plans stay feasible.
+func (planner *Planner) Plan(body *pose.Body, start, goal pose.Ground) (*Plan, error) {
+	if start == goal {
+		return planner.schedule(body, start, goal, 0), nil
+	}
+	if planner.MaxCycles < 1 {
+		return nil, fmt.Errorf("footstep: goal needs more than %d cycles", planner.MaxCycles)
+	}
+
+	infeasible, cycles := 0, 1
+	var plan *Plan
+	for {
+		plan = planner.schedule(body, start, goal, cycles)
+		if planner.feasible(plan) {
+			break
+		}
+		if cycles >= planner.MaxCycles {
+			return nil, fmt.Errorf("footstep: goal needs more than %d cycles", planner.MaxCycles)
+		}
+		infeasible, cycles = cycles, min(cycles*2, planner.MaxCycles)
+	}
+
+	for cycles-infeasible > 1 {
+		middle := (infeasible + cycles) / 2
+		candidate := planner.schedule(body, start, goal, middle)
+		if planner.feasible(candidate) {
+			plan, cycles = candidate, middle
+		} else {
+			infeasible = middle
+		}
+	}
+	return plan, nil
+}
+
+// stance is a foothold that is held over a time interval.
+type stance struct {
+	leg      int
+	foothold g.Vec
+	from, to float32
+}
+
+// schedule places footholds when the body moves in the given number of cycles.
+//
+// Each leg steps to its home position relative to the body in the middle
+// of the following stance, until it reaches the goal.
+func (planner *Planner) schedule(body *pose.Body, start, goal pose.Ground, cycles int) *Plan {
+	plan := &Plan{Start: start, Goal: goal, Cycles: cycles}
+	if cycles == 0 {
+		return plan
+	}
+
+	duty := planner.Gait.DutyFactor
+	steps := map[int]*Step{}
+	for i, leg := range body.Legs() {
+		offset := planner.Gait.Offset(leg)
+		for k := 0; ; k++ {
+			liftOff := float32(k) + offset
+			touchDown := liftOff + 1 - duty
+			middle := min(touchDown+duty/2, float32(cycles))
+
+			tick := ticks(liftOff)
+			step, ok := steps[tick]
+			if !ok {
+				step = &Step{
+					LiftOff:   liftOff,
+					TouchDown: touchDown,
+					Body:      plan.At(touchDown),
+				}
+				steps[tick] = step
+			}
+			step.Legs = append(step.Legs, i)
+			step.Footholds = append(step.Footholds, plan.At(middle).World(planner.Homes[i]))
+
+			if middle >= float32(cycles) {
+				break
+			}
+		}
+	}
+
+	for _, step := range steps {
+		plan.Steps = append(plan.Steps, *step)
+	}
+	sort.Slice(plan.Steps, func(i, k int) bool {
+		return plan.Steps[i].LiftOff < plan.Steps[k].LiftOff
+	})
+	return plan
+}
+
+// ticksPerCycle is the resolution used for grouping legs that lift off together,
+// it's divisible by the number of legs and common gait phase counts.
+const ticksPerCycle = 720
+
+// ticks converts a time in cycles to the nearest tick.
+func ticks(t float32) int {
+	return int(math.Round(float64(t) * ticksPerCycle))
+}
+
+// feasible checks whether all the footholds stay within the footprints
+// while the legs are on the ground.
+func (planner *Planner) feasible(plan *Plan) bool {
+	for _, stance := range planner.stances(plan) {
+		const samples = 8
+		for i := 0; i <= samples; i++ {
+			t := stance.from + (stance.to-stance.from)*float32(i)/samples
+			local := plan.At(t).Local(stance.foothold)
+			if !planner.inside(planner.Footprints[stance.leg], local) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// stances lists all the footholds of the plan with the time they are held.
+func (planner *Planner) stances(plan *Plan) []stance {
+	end := float32(plan.Cycles)
+	for _, step := range plan.Steps {
+		end = max(end, step.TouchDown)
+	}
+
+	var stances []stance
+	for i, home := range planner.Homes {
+		current := stance{leg: i, foothold: plan.Start.World(home)}
+		for _, step := range plan.Steps {
+			for k, leg := range step.Legs {
+				if leg != i {
+					continue
+				}
+				current.to = step.LiftOff
+				stances = append(stances, current)
+				current = stance{leg: i, foothold: step.Footholds[k], from: step.TouchDown}
+			}
+		}
+		current.to = end
+		stances = append(stances, current)
+	}
+	return stances
+}
+
+// inside checks whether p is inside the footprint with enough margin.
+func (planner *Planner) inside(footprint g.Polygon, p g.Vec) bool {
+	return footprint.Contains(p) && footprint.Distance(p) >= planner.Margin
+}
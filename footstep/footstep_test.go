@@ -0,0 +1,199 @@
+package footstep_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/footstep"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/gait"
+	"github.com/egonelbre/hexapod/ik/legik"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+func TestPlan(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Origin = g.Vec{Y: body.Size.Y}
+
+	start := pose.Ground{Position: g.Vec{X: 10 * g.MM, Z: -20 * g.MM}, Yaw: g.Tau / 16}
+	goals := []pose.Ground{
+		start,
+		{Position: g.Vec{X: 50 * g.MM}, Yaw: g.Tau / 16},
+		{Position: g.Vec{X: 200 * g.MM, Z: 100 * g.MM}, Yaw: g.Tau / 8},
+		{Position: g.Vec{X: -100 * g.MM, Z: 50 * g.MM}, Yaw: -g.Tau / 4},
+		{Position: start.Position, Yaw: g.Tau / 2},
+	}
+
+	for _, c := range gait.Gaits {
+		planner, err := footstep.NewPlanner(body, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, goal := range goals {
+			plan, err := planner.Plan(body, start, goal)
+			if err != nil {
+				t.Errorf("%s %v: %v", c.Name, goal, err)
+				continue
+			}
+			if goal == start {
+				if len(plan.Steps) != 0 {
+					t.Errorf("%s: got %d steps for not moving", c.Name, len(plan.Steps))
+				}
+				continue
+			}
+
+			final := map[int]g.Vec{}
+			for _, step := range plan.Steps {
+				if step.TouchDown <= step.LiftOff {
+					t.Errorf("%s %v: invalid step timing %v", c.Name, goal, step)
+				}
+
+				body.Origin.X, body.Origin.Z = step.Body.Position.X, step.Body.Position.Z
+				body.Orient.Yaw = step.Body.Yaw
+				legs := body.Legs()
+				for k, i := range step.Legs {
+					final[i] = step.Footholds[k]
+					if !legik.Reachable(body, legs[i], step.Footholds[k]) {
+						t.Errorf("%s %v: %s cannot reach %v", c.Name, goal, legs[i].Name, step.Footholds[k])
+					}
+				}
+			}
+
+			for i, home := range planner.Homes {
+				if d := final[i].Distance(goal.World(home)); d > 0.01*g.MM {
+					t.Errorf("%s %v: leg %d ends %.3fmm from home", c.Name, goal, i, d.Millimeters())
+				}
+			}
+		}
+	}
+}
+
+func TestPlanLonger(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Origin = g.Vec{Y: body.Size.Y}
+
+	planner, err := footstep.NewPlanner(body, gait.Tripod)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := 0
+	for _, distance := range []g.Length{20 * g.MM, 100 * g.MM, 300 * g.MM} {
+		plan, err := planner.Plan(body, pose.Ground{}, pose.Ground{Position: g.Vec{X: distance}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plan.Cycles < previous {
+			t.Errorf("%.0fmm: got %d cycles; exp at least %d", distance.Millimeters(), plan.Cycles, previous)
+		}
+		previous = plan.Cycles
+
+		velocity, turn := plan.Velocity(0, 1)
+		if exp := distance.Scale(1 / float32(plan.Cycles)); g.Abs(velocity.X-exp) > 0.01*g.MM || turn != 0 {
+			t.Errorf("%.0fmm: velocity got %v %v; exp %v", distance.Millimeters(), velocity, turn, exp)
+		}
+	}
+	if previous < 3 {
+		t.Errorf("got %d cycles for a long walk", previous)
+	}
+}
+
+func TestHomeUnreachable(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Origin = g.Vec{Y: 200 * g.MM}
+
+	if _, err := footstep.NewPlanner(body, gait.Tripod); !errors.Is(err, footstep.ErrHomeUnreachable) {
+		t.Errorf("got %v; exp %v", err, footstep.ErrHomeUnreachable)
+	}
+}
+
+func TestPlanGroups(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Origin = g.Vec{Y: body.Size.Y}
+
+	// offsets that differ only by rounding still lift off together
+	near, far := float32(0.7), float32(0.4)
+	pairs := gait.Table("pairs", 1.0/2.0, map[string]float32{
+		"RF": 0.3, "LB": near - far, "RM": 0.3,
+		"LM": 0.8, "RB": 0.8, "LF": 0.8,
+	})
+
+	legs := map[string]int{"tripod": 3, "ripple": 1, "wave": 1, "pairs": 3}
+	for _, c := range append(gait.Gaits, pairs) {
+		planner, err := footstep.NewPlanner(body, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan, err := planner.Plan(body, pose.Ground{}, pose.Ground{Position: g.Vec{X: 50 * g.MM}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, step := range plan.Steps {
+			if len(step.Legs) != legs[c.Name] {
+				t.Errorf("%s: step at %v moves %d legs; exp %d", c.Name, step.LiftOff, len(step.Legs), legs[c.Name])
+			}
+		}
+	}
+}
+
+func TestFollowPlan(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Origin = g.Vec{Y: body.Size.Y}
+
+	planner, err := footstep.NewPlanner(body, gait.Tripod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goal := pose.Ground{Position: g.Vec{X: 80 * g.MM, Z: 30 * g.MM}}
+	plan, err := planner.Plan(body, pose.Ground{}, goal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// footholds of each leg in the order they are stepped on
+	footholds := make([][]g.Vec, len(planner.Homes))
+	for _, step := range plan.Steps {
+		for k, i := range step.Legs {
+			footholds[i] = append(footholds[i], step.Footholds[k])
+		}
+	}
+
+	engine := gait.NewEngine(body, planner.Gait)
+	engine.Footholds = plan
+
+	landed := make([]int, len(engine.Legs))
+	const dt = 1.0 / 60
+	for frame := 0; engine.Time < float32(plan.Cycles)+1; frame++ {
+		body.World = body.World.Move(engine.CurrentVelocity, engine.CurrentTurnRate, dt)
+		engine.Velocity, engine.TurnRate = plan.Velocity(engine.Time+dt/engine.Period, engine.Period)
+
+		swinging := make([]bool, len(engine.Legs))
+		for i, state := range engine.Legs {
+			swinging[i] = state.Swing
+		}
+		engine.Update(body, dt)
+
+		for i, state := range engine.Legs {
+			if !swinging[i] || state.Swing || landed[i] >= len(footholds[i]) {
+				continue
+			}
+			foot := body.World.World(state.Target)
+			exp := footholds[i][landed[i]]
+			if d := foot.Distance(exp); d > 1*g.MM {
+				t.Errorf("frame %d, leg %d: landed at %v; exp %v", frame, i, foot, exp)
+			}
+			landed[i]++
+		}
+	}
+
+	for i := range landed {
+		if landed[i] != len(footholds[i]) {
+			t.Errorf("leg %d: landed %d times; exp %d", i, landed[i], len(footholds[i]))
+		}
+	}
+	if d := body.World.Position.Distance(goal.Position); d > 1*g.MM {
+		t.Errorf("body got %v; exp %v", body.World, goal)
+	}
+}
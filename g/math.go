@@ -1,6 +1,9 @@
 package g
 
-import "math"
+import (
+	"math"
+	"strconv"
+)
 
 // Fixed point lengths
 const (
@@ -16,12 +19,36 @@ func (length Length) Millimeters() float32 { return length.Float32() / MM.Float3
 func (length Length) Float64() float64 { return float64(length) }
 func (length Length) Float32() float32 { return float32(length) }
 
+// MarshalJSON encodes length in millimeters.
+func (length Length) MarshalJSON() ([]byte, error) {
+	return strconv.AppendFloat(nil, float64(length.Millimeters()), 'g', -1, 32), nil
+}
+
+// UnmarshalJSON decodes length from millimeters, null is ignored.
+func (length *Length) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	mm, err := strconv.ParseFloat(string(data), 32)
+	if err != nil {
+		return err
+	}
+	*length = Length(float32(mm) * MM.Float32())
+	return nil
+}
+
 func (length Length) Scale(v float32) Length { return Length(float32(length) * v) }
 func (length Length) Sqrt() Length           { return Length(math.Sqrt(float64(length))) }
 
-func Abs(v Length) Length {
+// Abs returns the absolute value of v.
+func Abs[T ~float32](v T) T {
 	if v < 0 {
 		return -v
 	}
 	return v
 }
+
+// Clamp limits v to range [lo, hi].
+func Clamp[T ~float32](v, lo, hi T) T {
+	return min(max(v, lo), hi)
+}
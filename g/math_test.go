@@ -0,0 +1,33 @@
+package g_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/egonelbre/hexapod/g"
+)
+
+func TestLengthJSON(t *testing.T) {
+	data, err := json.Marshal(12.5 * g.MM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "12.5" {
+		t.Errorf("marshal got %s; exp 12.5", data)
+	}
+
+	length := 3 * g.MM
+	if err := json.Unmarshal([]byte("null"), &length); err != nil {
+		t.Fatal(err)
+	}
+	if length != 3*g.MM {
+		t.Errorf("null got %v; exp %v", length, 3*g.MM)
+	}
+
+	if err := json.Unmarshal(data, &length); err != nil {
+		t.Fatal(err)
+	}
+	if length != 12.5*g.MM {
+		t.Errorf("unmarshal got %v; exp %v", length, 12.5*g.MM)
+	}
+}
@@ -0,0 +1,90 @@
+package g
+
+import "sort"
+
+// Polygon is a polygon on the ground plane, Y is ignored.
+type Polygon []Vec
+
+// Contains checks whether p is inside the polygon.
+func (polygon Polygon) Contains(p Vec) bool {
+	inside := false
+	j := len(polygon) - 1
+	for i := range polygon {
+		a, b := polygon[i], polygon[j]
+		if (a.Z > p.Z) != (b.Z > p.Z) {
+			x := a.X + (b.X-a.X)*(p.Z-a.Z)/(b.Z-a.Z)
+			if p.X < x {
+				inside = !inside
+			}
+		}
+		j = i
+	}
+	return inside
+}
+
+// ConvexHull returns the convex hull of points on the ground plane,
+// in counter-clockwise order when looking from above.
+func ConvexHull(points []Vec) Polygon {
+	sorted := append([]Vec{}, points...)
+	sort.Slice(sorted, func(i, k int) bool {
+		if sorted[i].X != sorted[k].X {
+			return sorted[i].X < sorted[k].X
+		}
+		return sorted[i].Z < sorted[k].Z
+	})
+	if len(sorted) < 3 {
+		return Polygon(sorted)
+	}
+
+	// Andrew's monotone chain
+	hull := make(Polygon, 0, 2*len(sorted))
+	for _, p := range sorted {
+		for len(hull) >= 2 && turn(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(sorted) - 2; i >= 0; i-- {
+		p := sorted[i]
+		for len(hull) >= lower && turn(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:len(hull)-1]
+}
+
+// turn returns the 2D cross product of ab and ac on the ground plane,
+// positive for a counter-clockwise turn when looking from above.
+func turn(a, b, c Vec) Length {
+	return (b.Z-a.Z)*(c.X-a.X) - (b.X-a.X)*(c.Z-a.Z)
+}
+
+// Distance returns the distance from p to the nearest edge of the polygon.
+func (polygon Polygon) Distance(p Vec) Length {
+	p.Y = 0
+	if len(polygon) == 1 {
+		a := polygon[0]
+		a.Y = 0
+		return a.Distance(p)
+	}
+
+	best := Length(-1)
+	j := len(polygon) - 1
+	for i := range polygon {
+		a, b := polygon[j], polygon[i]
+		a.Y, b.Y = 0, 0
+
+		ab := b.Sub(a)
+		t := float32(0)
+		if length2 := ab.Length2(); length2 > 0 {
+			t = min(max((p.Sub(a).Dot(ab)/length2).Float32(), 0), 1)
+		}
+		if d := a.Add(ab.Scale(t)).Distance(p); best < 0 || d < best {
+			best = d
+		}
+		j = i
+	}
+	return best
+}
@@ -51,11 +51,23 @@ func (a Vec) Scale(s float32) Vec {
 	}
 }
 
+func (a Vec) Lerp(b Vec, t float32) Vec {
+	return a.Add(b.Sub(a).Scale(t))
+}
+
 func (a Vec) Dot(b Vec) Length      { return a.X*b.X + a.Y*b.Y + a.Z*b.Z }
 func (a Vec) Length() Length        { return a.Dot(a).Sqrt() }
 func (a Vec) Length2() Length       { return a.Dot(a) }
 func (a Vec) Distance(b Vec) Length { return a.Sub(b).Length() }
 
+func (a Vec) Cross(b Vec) Vec {
+	return Vec{
+		X: a.Y*b.Z - a.Z*b.Y,
+		Y: a.Z*b.X - a.X*b.Z,
+		Z: a.X*b.Y - a.Y*b.X,
+	}
+}
+
 func (a Vec) Meters() struct{ X, Y, Z float32 } {
 	return struct{ X, Y, Z float32 }{a.X.Meters(), a.Y.Meters(), a.Z.Meters()}
 }
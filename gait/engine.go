@@ -0,0 +1,311 @@
+package gait
+
+import (
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/pose"
+	"github.com/egonelbre/hexapod/swing"
+)
+
+// Engine moves feet according to a gait and desired body motion.
+//
+// Foot targets are in body ground space, where the origin is under the body
+// center and axes are aligned with the body yaw. Feet touch down on the
+// terrain of the body.
+//
+// Gait and Velocity can be changed at any time, the engine blends towards
+// them over several cycles. A leg never lifts off while one of its
+// neighbours is in the air.
+type Engine struct {
+	Gait Gait
+	// Period is the duration of a single step cycle in seconds.
+	Period float32
+	// Swing is the shape of the foot trajectory in the air.
+	Swing swing.Shape
+	// Transition is the number of cycles used to blend into a new gait.
+	// Leg offsets are only adjusted while the leg is planted.
+	Transition float32
+
+	// Velocity is the desired body velocity in body ground space, per second.
+	Velocity g.Vec
+	// TurnRate is the desired rotation around Y, per second.
+	TurnRate g.Radians
+	// Acceleration limits the change of velocity, per second², zero means no limit.
+	Acceleration g.Length
+	// TurnAcceleration limits the change of turn rate, per second², zero means no limit.
+	TurnAcceleration g.Radians
+
+	// Footholds chooses where swinging legs touch down, nil steps
+	// around Home.
+	Footholds Footholds
+
+	// CurrentVelocity is the velocity the feet are currently moved with.
+	CurrentVelocity g.Vec
+	// CurrentTurnRate is the turn rate the feet are currently moved with.
+	CurrentTurnRate g.Radians
+	// DutyFactor is the current duty factor, blended towards Gait.DutyFactor.
+	DutyFactor float32
+
+	// Cycle is the current phase of the step cycle, [0, 1).
+	Cycle float32
+	// Time is the number of cycles since the engine was created.
+	Time float32
+	// Legs contains the state of each leg, in the same order as pose.Body.Legs().
+	Legs []Leg
+
+	// blending from the previous gait
+	gaitDuty    float32
+	gaitOffsets []float32
+	dutyRate    float32
+}
+
+// Leg is the state of a single leg.
+type Leg struct {
+	// Home is the neutral foot position.
+	Home g.Vec
+	// Offset is the current lift-off offset, blended towards the gait offset.
+	Offset float32
+	// Swing is whether the leg is in the swing phase.
+	Swing bool
+	// Touched is whether the leg touched down before the end of the swing.
+	Touched bool
+	// Progress is the fraction of the swing or stance completed.
+	Progress float32
+	// LiftOff is where the swing started.
+	LiftOff g.Vec
+	// LiftOffTime is the engine Time when the swing started.
+	LiftOffTime float32
+	// Target is the current foot target.
+	Target g.Vec
+
+	initialized bool
+}
+
+// Footholds plans where the legs touch down.
+type Footholds interface {
+	// TouchDown returns the touch-down position in body ground space for
+	// the swing of leg that lifted off at liftOff, where the current time
+	// is now. Both are engine Time. The leg is an index of pose.Body.Legs().
+	//
+	// When ok is false, the engine steps around the leg Home.
+	TouchDown(leg int, liftOff, now float32) (target g.Vec, ok bool)
+}
+
+// NewEngine creates an engine with feet at their home positions.
+func NewEngine(body *pose.Body, gait Gait) *Engine {
+	engine := &Engine{
+		Gait:       gait,
+		Period:     1,
+		Swing:      swing.Shape{Curve: swing.Cycloid, Height: 20 * g.MM},
+		Transition: 2,
+		DutyFactor: gait.DutyFactor,
+	}
+	engine.gaitChanged(body.Legs())
+	for _, leg := range body.Legs() {
+		home := Home(leg)
+		engine.Legs = append(engine.Legs, Leg{
+			Home:    home,
+			Offset:  gait.Offset(leg),
+			LiftOff: home,
+			Target:  home,
+		})
+	}
+	return engine
+}
+
+// Home returns the default neutral foot position for the leg.
+func Home(leg *pose.Leg) g.Vec {
+	home := leg.Offset.Add(leg.Offset.NormalizedTo(70 * g.MM))
+	home.Y = 0
+	return home
+}
+
+// Update advances the gait by dt seconds and updates leg IK targets.
+func (engine *Engine) Update(body *pose.Body, dt float32) {
+	step := dt / engine.Period
+	engine.Cycle = wrap(engine.Cycle + step)
+	engine.Time += step
+
+	legs := body.Legs()
+	if engine.gaitChanged(legs) {
+		engine.dutyRate = g.Abs(engine.Gait.DutyFactor-engine.DutyFactor) / engine.transition()
+	}
+	engine.DutyFactor = approach(engine.DutyFactor, engine.Gait.DutyFactor, engine.dutyRate*step)
+
+	duty := engine.DutyFactor
+	stanceTime := duty * engine.Period
+
+	ground := body.Ground()
+
+	// decide which legs are in the air, before moving any of them
+	swinging := make([]bool, len(legs))
+	for i, leg := range legs {
+		state := &engine.Legs[i]
+		if state.initialized && !state.Swing {
+			engine.rephase(state, engine.Gait.Offset(leg), 0.5*step/engine.transition())
+		}
+		swinging[i] = wrap(engine.Cycle-state.Offset) < 1-duty
+	}
+	for i := range legs {
+		state := &engine.Legs[i]
+		if swinging[i] && !state.Swing && state.initialized && neighbourSwinging(swinging, i) {
+			// hold the leg on the ground until the neighbours touch down
+			state.Offset = engine.Cycle
+			swinging[i] = false
+		}
+	}
+
+	for i, leg := range legs {
+		state := &engine.Legs[i]
+		local := wrap(engine.Cycle - state.Offset)
+
+		if swinging[i] {
+			if !state.initialized {
+				state.Target.Y = ground.Height(state.Target.X, state.Target.Z)
+			}
+			if !state.Swing || !state.initialized {
+				state.LiftOff = state.Target
+				state.LiftOffTime = engine.Time - local
+				state.Touched = false
+			}
+			state.Progress = local / (1 - duty)
+
+			if !state.Touched {
+				touchDown := engine.touchDown(i, state, stanceTime)
+				touchDown.Y = ground.Height(touchDown.X, touchDown.Z)
+
+				// clear the higher end of a step
+				shape := engine.Swing
+				shape.Height += g.Abs(touchDown.Y - state.LiftOff.Y)
+
+				traj := shape.Between(state.LiftOff, touchDown, (1-duty)*engine.Period)
+				foot := traj.At(state.Progress)
+				state.Target, state.Touched = traj.Sample(state.Progress, ground.Height(foot.X, foot.Z))
+			} else {
+				// wait on the ground for the stance
+				state.Target = engine.displace(state.Target, dt)
+			}
+		} else {
+			state.Progress = g.Clamp((local-(1-duty))/duty, 0, 1)
+			if state.initialized {
+				// keep the foot fixed on the ground
+				state.Target = engine.displace(state.Target, dt)
+				if state.Swing {
+					// the swing may end slightly above the ground
+					state.Target.Y = ground.Height(state.Target.X, state.Target.Z)
+				}
+			} else {
+				state.Target = engine.displace(state.Home, (state.Progress-0.5)*stanceTime)
+				state.Target.Y = ground.Height(state.Target.X, state.Target.Z)
+			}
+		}
+
+		state.Swing = swinging[i]
+		state.initialized = true
+
+		leg.IK.Target = state.Target
+		leg.IK.Planted = !swinging[i] || state.Touched
+	}
+
+	engine.accelerate(dt)
+}
+
+// touchDown returns where the swinging leg should land, by default half
+// a stance ahead of Home.
+func (engine *Engine) touchDown(i int, state *Leg, stanceTime float32) g.Vec {
+	if engine.Footholds != nil {
+		if target, ok := engine.Footholds.TouchDown(i, state.LiftOffTime, engine.Time); ok {
+			return target
+		}
+	}
+	return engine.displace(state.Home, -stanceTime/2)
+}
+
+// gaitChanged checks whether Gait differs from the previous call, by
+// comparing the duty factor and the leg offsets.
+func (engine *Engine) gaitChanged(legs []*pose.Leg) bool {
+	changed := engine.Gait.DutyFactor != engine.gaitDuty || len(legs) != len(engine.gaitOffsets)
+	for i := 0; i < len(legs) && !changed; i++ {
+		changed = engine.Gait.Offset(legs[i]) != engine.gaitOffsets[i]
+	}
+	if !changed {
+		return false
+	}
+
+	engine.gaitDuty = engine.Gait.DutyFactor
+	engine.gaitOffsets = engine.gaitOffsets[:0]
+	for _, leg := range legs {
+		engine.gaitOffsets = append(engine.gaitOffsets, engine.Gait.Offset(leg))
+	}
+	return true
+}
+
+// transition returns Transition, avoiding division by zero.
+func (engine *Engine) transition() float32 {
+	return max(engine.Transition, 1e-3)
+}
+
+// rephase moves a planted leg offset towards the target by at most rate.
+//
+// The leg is not pushed back into the swing, since it has just touched down.
+func (engine *Engine) rephase(state *Leg, target, rate float32) {
+	delta := g.Clamp(cyclicDelta(state.Offset, target), -rate, rate)
+	if delta == 0 {
+		return
+	}
+
+	swingEnd := 1 - engine.DutyFactor
+	local := wrap(engine.Cycle - state.Offset)
+	if delta > 0 && local >= swingEnd && local-delta < swingEnd {
+		state.Offset = wrap(engine.Cycle - swingEnd)
+		return
+	}
+	state.Offset = wrap(state.Offset + delta)
+}
+
+// neighbourSwinging checks whether an adjacent leg is in the air.
+//
+// pose.Body.Legs() goes around the body, hence neighbours are next to
+// each other in the list.
+func neighbourSwinging(swing []bool, i int) bool {
+	n := len(swing)
+	return swing[(i+1)%n] || swing[(i+n-1)%n]
+}
+
+// accelerate moves current velocity and turn rate towards the desired.
+//
+// Both are changed by the same fraction, such that the body keeps turning
+// around the same center.
+func (engine *Engine) accelerate(dt float32) {
+	change := engine.Velocity.Sub(engine.CurrentVelocity)
+	turn := engine.TurnRate - engine.CurrentTurnRate
+
+	fraction := float32(1)
+	if limit := engine.Acceleration.Scale(dt); engine.Acceleration > 0 && change.Length() > limit {
+		fraction = min(fraction, (limit / change.Length()).Float32())
+	}
+	if limit := engine.TurnAcceleration * dt; engine.TurnAcceleration > 0 && g.Abs(turn) > limit {
+		fraction = min(fraction, limit/g.Abs(turn))
+	}
+
+	engine.CurrentVelocity = engine.CurrentVelocity.Add(change.Scale(fraction))
+	engine.CurrentTurnRate += turn * fraction
+}
+
+// displace calculates where a point on the ground ends up relative to the
+// body after moving dt seconds. The height of the point is kept.
+func (engine *Engine) displace(p g.Vec, dt float32) g.Vec {
+	moved := g.RotateY(-engine.CurrentTurnRate * dt).Transform(p)
+	moved = moved.Sub(engine.CurrentVelocity.Scale(dt))
+	moved.Y = p.Y
+	return moved
+}
+
+// approach moves v towards target by at most rate.
+func approach(v, target, rate float32) float32 {
+	return v + g.Clamp(target-v, -rate, rate)
+}
+
+// cyclicDelta returns the shortest change from a to b, in range [-0.5, 0.5).
+func cyclicDelta(a, b float32) float32 {
+	return wrap(b-a+0.5) - 0.5
+}
@@ -0,0 +1,71 @@
+// package gait generates foot targets for walking
+package gait
+
+import (
+	"math"
+
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+// Gait describes when each leg is lifted during a step cycle.
+type Gait struct {
+	Name string
+	// DutyFactor is the fraction of the cycle a leg is on the ground.
+	DutyFactor float32
+	// Offset returns when the leg lifts off, as a fraction of the cycle.
+	Offset func(leg *pose.Leg) float32
+}
+
+var (
+	// Tripod alternates between two sets of three legs.
+	Tripod = Gait{
+		Name:       "tripod",
+		DutyFactor: 1.0 / 2.0,
+		Offset: func(leg *pose.Leg) float32 {
+			// round to the nearest half cycle
+			return wrap(float32(math.Floor(float64(Phase(leg))*2+0.25)) / 2)
+		},
+	}
+	// Ripple lifts two legs at a time, on opposite sides.
+	Ripple = Gait{
+		Name:       "ripple",
+		DutyFactor: 2.0 / 3.0,
+		Offset:     Phase,
+	}
+	// Wave lifts a single leg at a time.
+	Wave = Gait{
+		Name:       "wave",
+		DutyFactor: 5.0 / 6.0,
+		Offset:     Phase,
+	}
+)
+
+// Gaits lists all predefined gaits.
+var Gaits = []Gait{Tripod, Ripple, Wave}
+
+// Table creates a gait from lift-off offsets indexed by leg name.
+// Offsets are fractions of the cycle.
+func Table(name string, dutyFactor float32, offsets map[string]float32) Gait {
+	return Gait{
+		Name:       name,
+		DutyFactor: dutyFactor,
+		Offset: func(leg *pose.Leg) float32 {
+			return wrap(offsets[leg.Name])
+		},
+	}
+}
+
+// Phase returns leg.Phase as a fraction of the cycle.
+func Phase(leg *pose.Leg) float32 {
+	return wrap(-leg.Phase / g.Tau)
+}
+
+// wrap wraps v into range [0, 1).
+func wrap(v float32) float32 {
+	v -= float32(math.Floor(float64(v)))
+	if v >= 1 {
+		v = 0
+	}
+	return v
+}
@@ -0,0 +1,240 @@
+package gait_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/gait"
+	"github.com/egonelbre/hexapod/terrain"
+)
+
+func TestTripodGroups(t *testing.T) {
+	body := adeept.ZeroPose()
+	groups := map[string]float32{
+		"LF": 0, "RM": 0, "LB": 0,
+		"RF": 0.5, "LM": 0.5, "RB": 0.5,
+	}
+	for _, leg := range body.Legs() {
+		if got := gait.Tripod.Offset(leg); got != groups[leg.Name] {
+			t.Errorf("%s: got %v; exp %v", leg.Name, got, groups[leg.Name])
+		}
+	}
+}
+
+func TestSupport(t *testing.T) {
+	custom := gait.Table("custom", 0.75, map[string]float32{
+		"RF": 0, "LB": 0,
+		"LF": 0.25, "RB": 0.25,
+		"RM": 0.5, "LM": 0.75,
+	})
+
+	type Case struct {
+		Gait    gait.Gait
+		Planted int
+	}
+	cases := []Case{
+		{gait.Tripod, 3},
+		{gait.Ripple, 4},
+		{gait.Wave, 5},
+		{custom, 4},
+	}
+
+	for _, c := range cases {
+		body := adeept.ZeroPose()
+		engine := gait.NewEngine(body, c.Gait)
+		engine.Velocity = g.Vec{X: 30 * g.MM}
+
+		// avoid sampling exactly at lift-off and touch-down
+		const dt = 1.0 / 50
+		for frame := 0; frame < 100; frame++ {
+			engine.Update(body, dt)
+
+			planted := 0
+			for _, leg := range body.Legs() {
+				if leg.IK.Planted {
+					planted++
+					if leg.IK.Target.Y != 0 {
+						t.Errorf("%s: planted foot above ground %v", c.Gait.Name, leg.IK.Target)
+					}
+				}
+			}
+			if planted < c.Planted {
+				t.Errorf("%s: frame %d, only %d planted", c.Gait.Name, frame, planted)
+			}
+		}
+	}
+}
+
+func TestStanceDoesNotSlip(t *testing.T) {
+	body := adeept.ZeroPose()
+	engine := gait.NewEngine(body, gait.Ripple)
+	engine.Velocity = g.Vec{X: 20 * g.MM, Z: 10 * g.MM}
+	engine.TurnRate = g.Tau / 16
+
+	// track the body in world space
+	var position g.Vec
+	var yaw g.Radians
+	world := func(p g.Vec) g.Vec {
+		return position.Add(g.RotateY(yaw).Transform(p))
+	}
+
+	const dt = 1.0 / 60
+	engine.Update(body, dt)
+	previous := make([]g.Vec, len(engine.Legs))
+	planted := make([]bool, len(engine.Legs))
+	for i, leg := range engine.Legs {
+		previous[i], planted[i] = world(leg.Target), !leg.Swing
+	}
+
+	for frame := 0; frame < 180; frame++ {
+		yaw += engine.TurnRate * dt
+		position = position.Add(g.RotateY(yaw).Transform(engine.Velocity.Scale(dt)))
+		engine.Update(body, dt)
+
+		for i, leg := range engine.Legs {
+			current := world(leg.Target)
+			if planted[i] && !leg.Swing {
+				if d := current.Distance(previous[i]); d > 0.1*g.MM {
+					t.Errorf("frame %d, leg %d: slipped %.3fmm", frame, i, d.Millimeters())
+				}
+			}
+			previous[i], planted[i] = current, !leg.Swing
+		}
+	}
+}
+
+func TestTransition(t *testing.T) {
+	type Case struct{ From, To gait.Gait }
+	cases := []Case{
+		{gait.Wave, gait.Tripod},
+		{gait.Tripod, gait.Wave},
+		{gait.Ripple, gait.Tripod},
+		{gait.Tripod, gait.Ripple},
+		// tables without a name
+		{unnamed(gait.Wave), unnamed(gait.Tripod)},
+		{unnamed(gait.Tripod), unnamed(gait.Ripple)},
+	}
+
+	for i, c := range cases {
+		name := fmt.Sprintf("%d:%s-%s", i, c.From.Name, c.To.Name)
+		body := adeept.ZeroPose()
+		engine := gait.NewEngine(body, c.From)
+		engine.Velocity = g.Vec{X: 30 * g.MM}
+
+		const dt = 1.0 / 50
+		for frame := 0; frame < 50; frame++ {
+			engine.Update(body, dt)
+		}
+
+		engine.Gait = c.To
+		engine.Velocity = g.Vec{X: 40 * g.MM, Z: 10 * g.MM}
+		engine.Acceleration = 40 * g.MM
+
+		previous := make([]g.Vec, len(engine.Legs))
+		for i, leg := range engine.Legs {
+			previous[i] = leg.Target
+		}
+
+		for frame := 0; frame < 500; frame++ {
+			engine.Update(body, dt)
+
+			planted := 0
+			for i, leg := range engine.Legs {
+				if !leg.Swing {
+					planted++
+				}
+				next := engine.Legs[(i+1)%len(engine.Legs)]
+				if leg.Swing && next.Swing {
+					t.Errorf("%s: frame %d, neighbours %d and %d both in the air", name, frame, i, i+1)
+				}
+				if d := leg.Target.Distance(previous[i]); d > 15*g.MM {
+					t.Errorf("%s: frame %d, leg %d jumped %.3fmm", name, frame, i, d.Millimeters())
+				}
+				previous[i] = leg.Target
+			}
+			if planted < 3 {
+				t.Errorf("%s: frame %d, only %d planted", name, frame, planted)
+			}
+		}
+
+		if engine.CurrentVelocity != engine.Velocity {
+			t.Errorf("%s: velocity got %v; exp %v", name, engine.CurrentVelocity, engine.Velocity)
+		}
+		if engine.DutyFactor != c.To.DutyFactor {
+			t.Errorf("%s: duty factor got %v; exp %v", name, engine.DutyFactor, c.To.DutyFactor)
+		}
+		for i, leg := range body.Legs() {
+			if got, exp := engine.Legs[i].Offset, c.To.Offset(leg); g.Abs(got-exp) > 1e-4 {
+				t.Errorf("%s: %s offset got %v; exp %v", name, leg.Name, got, exp)
+			}
+		}
+	}
+}
+
+// unnamed converts the gait into an unnamed table.
+func unnamed(pattern gait.Gait) gait.Gait {
+	offsets := map[string]float32{}
+	for _, leg := range adeept.ZeroPose().Legs() {
+		offsets[leg.Name] = pattern.Offset(leg)
+	}
+	return gait.Table("", pattern.DutyFactor, offsets)
+}
+
+func TestAcceleration(t *testing.T) {
+	body := adeept.ZeroPose()
+	engine := gait.NewEngine(body, gait.Tripod)
+	engine.Acceleration = 50 * g.MM
+	engine.Velocity = g.Vec{X: 100 * g.MM}
+
+	const dt = 1.0 / 50
+	for frame := 1; frame <= 150; frame++ {
+		engine.Update(body, dt)
+
+		exp := min(50*g.MM.Scale(float32(frame)*dt), 100*g.MM)
+		if d := g.Abs(engine.CurrentVelocity.X - exp); d > 0.01*g.MM {
+			t.Fatalf("frame %d: velocity got %v; exp %v", frame, engine.CurrentVelocity.X, exp)
+		}
+	}
+}
+
+func TestTerrain(t *testing.T) {
+	// a step up along the walking direction
+	step, err := terrain.NewHeightmap(g.Vec{X: -200 * g.MM, Z: -200 * g.MM}, 10*g.MM, 60, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := 25; x < step.Columns; x++ {
+		for z := 0; z < step.Rows; z++ {
+			step.Set(x, z, 15*g.MM)
+		}
+	}
+
+	body := adeept.ZeroPose()
+	body.Terrain = step
+	engine := gait.NewEngine(body, gait.Ripple)
+	engine.Velocity = g.Vec{X: 30 * g.MM}
+
+	const dt = 1.0 / 60
+	for frame := 0; frame < 360; frame++ {
+		body.World = body.World.Move(engine.CurrentVelocity, engine.CurrentTurnRate, dt)
+		engine.Update(body, dt)
+
+		ground := body.Ground()
+		for _, leg := range body.Legs() {
+			target := leg.IK.Target
+			height := ground.Height(target.X, target.Z)
+			if leg.IK.Planted && !terrain.Planted(ground, target) {
+				t.Errorf("frame %d, %s: planted %v above ground %v", frame, leg.Name, target.Y, height)
+			}
+			if target.Y < height-0.1*g.MM {
+				t.Errorf("frame %d, %s: %v below ground %v", frame, leg.Name, target.Y, height)
+			}
+		}
+	}
+
+	if body.World.Position.X < 100*g.MM {
+		t.Fatalf("did not walk onto the step: %v", body.World)
+	}
+}
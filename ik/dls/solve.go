@@ -0,0 +1,189 @@
+// package dls solves IK for arbitrary hinge chains using damped least squares
+package dls
+
+import (
+	"math"
+
+	"github.com/egonelbre/hexapod/fk"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/ik"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+// Options configures the iterative solver.
+type Options struct {
+	ik.Options
+
+	// Damping avoids large jumps near singularities.
+	Damping g.Length
+	// MaxStep limits hinge rotation per iteration.
+	MaxStep g.Radians
+}
+
+// DefaultOptions are used when options are not specified.
+var DefaultOptions = Options{
+	Options: ik.Options{
+		Iterations: 100,
+		Tolerance:  0.1 * g.MM,
+	},
+	Damping: 5 * g.MM,
+	MaxStep: g.Tau / 16,
+}
+
+// Result describes how well the solver reached the target.
+type Result = ik.Result
+
+// SolveLeg solves leg hinge angles to reach worldTarget.
+func SolveLeg(body *pose.Body, leg *pose.Leg, worldTarget g.Vec, opts *Options) Result {
+	return Solve(fk.LegSpace(body, leg), leg.Hinges(), worldTarget, opts)
+}
+
+// Solve solves hinge angles of the chain starting at root to reach target.
+//
+// Hinges are rotated by fk.Link, hence any number of hinges with any Axis
+// is supported. Hinge angles are kept in their Range.
+func Solve(root g.Mat, hinges []*pose.Hinge, target g.Vec, opts *Options) Result {
+	if opts == nil {
+		opts = &DefaultOptions
+	}
+
+	n := len(hinges)
+	jacobian := make([][3]float64, n)
+	locked := make([]bool, n)
+	step := make([]float64, n)
+
+	var result Result
+	for result.Iterations = 0; result.Iterations < opts.Iterations; result.Iterations++ {
+		effector := calculateJacobian(root, hinges, jacobian)
+
+		delta := target.Sub(effector)
+		result.Residual = delta.Length()
+		if result.Residual <= opts.Tolerance {
+			result.Solved = true
+			return result
+		}
+
+		for i := range locked {
+			locked[i] = false
+		}
+
+		// lock hinges that would be pushed further past their limit
+		for attempt := 0; attempt <= n; attempt++ {
+			dampedStep(jacobian, locked, delta, opts.Damping, step)
+
+			changed := false
+			for i, hinge := range hinges {
+				if locked[i] {
+					continue
+				}
+				moved := *hinge
+				moved.Angle += g.Radians(step[i])
+				if moved.Clamp() && moved.Angle == hinge.Angle {
+					locked[i] = true
+					changed = true
+				}
+			}
+			if !changed {
+				break
+			}
+		}
+
+		// limit the step size to stay in the linear region
+		largest := 0.0
+		for _, v := range step {
+			largest = math.Max(largest, math.Abs(v))
+		}
+		scale := 1.0
+		if largest > float64(opts.MaxStep) {
+			scale = float64(opts.MaxStep) / largest
+		}
+
+		for i, hinge := range hinges {
+			hinge.Angle += g.Radians(step[i] * scale)
+			hinge.Clamp()
+		}
+	}
+
+	effector := fk.Chain(root, hinges)
+	result.Residual = effector.Distance(target)
+	result.Solved = result.Residual <= opts.Tolerance
+	return result
+}
+
+// calculateJacobian calculates how the end effector moves per radian
+// of each hinge rotation and returns the end effector position.
+func calculateJacobian(root g.Mat, hinges []*pose.Hinge, jacobian [][3]float64) g.Vec {
+	origins := make([]g.Vec, len(hinges))
+	axes := make([]g.Vec, len(hinges))
+
+	transform := root
+	for i, hinge := range hinges {
+		origins[i] = transform.Transform(g.Vec{})
+		axes[i] = transform.Transform(hinge.Axis.Vector(g.M)).Sub(origins[i])
+		transform = transform.Mul(fk.Link(hinge))
+	}
+	effector := transform.Transform(g.Vec{})
+
+	for i := range hinges {
+		// hinges rotate clockwise around the axis
+		arm := effector.Sub(origins[i])
+		ax := axes[i].X.Float64() / g.M.Float64()
+		ay := axes[i].Y.Float64() / g.M.Float64()
+		az := axes[i].Z.Float64() / g.M.Float64()
+		jacobian[i] = [3]float64{
+			-(ay*arm.Z.Float64() - az*arm.Y.Float64()),
+			-(az*arm.X.Float64() - ax*arm.Z.Float64()),
+			-(ax*arm.Y.Float64() - ay*arm.X.Float64()),
+		}
+	}
+
+	return effector
+}
+
+// dampedStep calculates step = Jᵀ (J Jᵀ + λ² I)⁻¹ delta, ignoring locked hinges.
+func dampedStep(jacobian [][3]float64, locked []bool, delta g.Vec, damping g.Length, step []float64) {
+	var a [3][3]float64
+	for i, col := range jacobian {
+		if locked[i] {
+			continue
+		}
+		for r := 0; r < 3; r++ {
+			for c := 0; c < 3; c++ {
+				a[r][c] += col[r] * col[c]
+			}
+		}
+	}
+	lambda2 := damping.Float64() * damping.Float64()
+	for k := 0; k < 3; k++ {
+		a[k][k] += lambda2
+	}
+
+	x := solve3(a, [3]float64{delta.X.Float64(), delta.Y.Float64(), delta.Z.Float64()})
+
+	for i, col := range jacobian {
+		if locked[i] {
+			step[i] = 0
+			continue
+		}
+		step[i] = col[0]*x[0] + col[1]*x[1] + col[2]*x[2]
+	}
+}
+
+// solve3 solves a x = b for a symmetric positive definite a.
+func solve3(a [3][3]float64, b [3]float64) [3]float64 {
+	det := a[0][0]*(a[1][1]*a[2][2]-a[1][2]*a[2][1]) -
+		a[0][1]*(a[1][0]*a[2][2]-a[1][2]*a[2][0]) +
+		a[0][2]*(a[1][0]*a[2][1]-a[1][1]*a[2][0])
+
+	var x [3]float64
+	for k := 0; k < 3; k++ {
+		m := a
+		for r := 0; r < 3; r++ {
+			m[r][k] = b[r]
+		}
+		x[k] = (m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+			m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+			m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])) / det
+	}
+	return x
+}
@@ -0,0 +1,88 @@
+package dls_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/fk"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/ik/dls"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+func hinge(axis pose.Axis, length g.Length) *pose.Hinge {
+	return &pose.Hinge{
+		Axis:   axis,
+		Length: length,
+		Range:  pose.HingeRange{Min: -g.Tau / 4, Max: g.Tau / 4},
+	}
+}
+
+// randomize sets hinges to random angles within the range.
+func randomize(rng *rand.Rand, hinges []*pose.Hinge) {
+	for _, hinge := range hinges {
+		hinge.Angle = hinge.Range.Min + (hinge.Range.Max-hinge.Range.Min)*rng.Float32()
+	}
+}
+
+func testChain(t *testing.T, root g.Mat, hinges []*pose.Hinge) {
+	t.Helper()
+
+	rng := rand.New(rand.NewSource(1))
+
+	const N = 200
+	solved := 0
+	for i := 0; i < N; i++ {
+		randomize(rng, hinges)
+		target := fk.Chain(root, hinges)
+
+		for _, hinge := range hinges {
+			hinge.Angle = 0
+		}
+
+		r := dls.Solve(root, hinges, target, nil)
+		for k, hinge := range hinges {
+			if !hinge.InBounds() {
+				t.Errorf("%d: hinge %d out of bounds %v", i, k, hinge.Angle)
+			}
+		}
+		if d := fk.Chain(root, hinges).Distance(target); g.Abs(d-r.Residual) > 0.01*g.MM {
+			t.Errorf("%d: residual got %v; exp %v", i, r.Residual, d)
+		}
+		if r.Solved {
+			solved++
+		}
+	}
+
+	// joint limits can trap the solver in a local minimum
+	if solved < N*9/10 {
+		t.Errorf("solved %d out of %d", solved, N)
+	}
+}
+
+func TestSolveLeg(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Orient = g.Orient{Yaw: 0.2, Pitch: 0.1, Roll: -0.1}
+	for _, leg := range body.Legs() {
+		testChain(t, fk.LegSpace(body, leg), leg.Hinges())
+	}
+}
+
+func TestSolve4DOF(t *testing.T) {
+	testChain(t, g.Translate(0, 50*g.MM, 0), []*pose.Hinge{
+		hinge(pose.Y, 12*g.MM),
+		hinge(pose.Z, 38*g.MM),
+		hinge(pose.Z, 30*g.MM),
+		hinge(pose.Z, 25*g.MM),
+	})
+}
+
+func TestSolveGripper(t *testing.T) {
+	testChain(t, g.Identity(), []*pose.Hinge{
+		hinge(pose.Y, 20*g.MM),
+		hinge(pose.Z, 40*g.MM),
+		hinge(pose.X, 10*g.MM),
+		hinge(pose.Z, 30*g.MM),
+	})
+}
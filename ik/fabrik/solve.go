@@ -0,0 +1,144 @@
+// package fabrik solves IK for hinge chains using
+// Forward And Backward Reaching Inverse Kinematics
+package fabrik
+
+import (
+	"github.com/egonelbre/hexapod/fk"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/ik"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+// Options configures the iterative solver.
+type Options = ik.Options
+
+// DefaultOptions are used when options are not specified.
+var DefaultOptions = Options{
+	Iterations: 200,
+	Tolerance:  0.1 * g.MM,
+}
+
+// Result describes how well the solver reached the target.
+type Result = ik.Result
+
+// SolveLeg solves leg hinge angles to reach worldTarget.
+func SolveLeg(body *pose.Body, leg *pose.Leg, worldTarget g.Vec, opts *Options) Result {
+	return Solve(fk.LegSpace(body, leg), leg.Hinges(), worldTarget, opts)
+}
+
+// Solve solves hinge angles of the chain starting at root to reach target.
+//
+// Each iteration does an unconstrained backward and forward reaching pass
+// over joint positions and then recovers hinge angles from the root,
+// which keeps the chain on the hinge axes and within hinge Range.
+func Solve(root g.Mat, hinges []*pose.Hinge, target g.Vec, opts *Options) Result {
+	if opts == nil {
+		opts = &DefaultOptions
+	}
+
+	n := len(hinges)
+	lengths := make([]g.Length, n)
+	for i, hinge := range hinges {
+		lengths[i] = hinge.End().Length()
+	}
+	joints := make([]g.Vec, n+1)
+
+	var result Result
+	for result.Iterations = 0; result.Iterations < opts.Iterations; result.Iterations++ {
+		positions(root, hinges, joints)
+
+		result.Residual = joints[n].Distance(target)
+		if result.Residual <= opts.Tolerance {
+			result.Solved = true
+			return result
+		}
+
+		// backward reaching
+		joints[n] = target
+		for i := n - 1; i >= 0; i-- {
+			joints[i] = reach(joints[i+1], joints[i], lengths[i])
+		}
+
+		// forward reaching
+		joints[0] = root.Transform(g.Vec{})
+		for i := 0; i < n; i++ {
+			joints[i+1] = reach(joints[i], joints[i+1], lengths[i])
+		}
+
+		recoverAngles(root, hinges, joints)
+	}
+
+	result.Residual = fk.Chain(root, hinges).Distance(target)
+	result.Solved = result.Residual <= opts.Tolerance
+	return result
+}
+
+// positions calculates positions of each hinge and the end effector,
+// in the space that root transforms to.
+func positions(root g.Mat, hinges []*pose.Hinge, joints []g.Vec) {
+	transform := root
+	for i, hinge := range hinges {
+		joints[i] = transform.Transform(g.Vec{})
+		transform = transform.Mul(fk.Link(hinge))
+	}
+	joints[len(hinges)] = transform.Transform(g.Vec{})
+}
+
+// reach places a point at distance length from the center towards p.
+func reach(center, p g.Vec, length g.Length) g.Vec {
+	direction := p.Sub(center)
+	if direction.Length() == 0 {
+		return center
+	}
+	return center.Add(direction.NormalizedTo(length))
+}
+
+// recoverAngles rotates each hinge such that the rest of the chain points
+// towards the reached joints, as much as the hinge axis and range allow.
+//
+// Aligning the whole rest of the chain, instead of only the next joint,
+// matters when the following hinges cannot rotate out of the plane,
+// e.g. a short Coxa followed by Femur and Tibia.
+func recoverAngles(root g.Mat, hinges []*pose.Hinge, joints []g.Vec) {
+	transform := root
+	for i, hinge := range hinges {
+		origin := transform.Transform(g.Vec{})
+		axis := transform.Transform(hinge.Axis.Vector(g.MM)).Sub(origin)
+		zero := transform.Mul(fk.Rotate(hinge.Axis, hinge.Zero))
+
+		// find the rotation that best fits the rest of the chain
+		var sin, cos g.Length
+		local := g.Identity()
+		for k := i; k < len(hinges); k++ {
+			if k == i {
+				local = fk.Translate(hinge.End())
+			} else {
+				local = local.Mul(fk.Link(hinges[k]))
+			}
+
+			current := project(zero.Mul(local).Transform(g.Vec{}).Sub(origin), axis)
+			desired := project(joints[k+1].Sub(origin), axis)
+			if current.Length() == 0 || desired.Length() == 0 {
+				continue
+			}
+			current = current.NormalizedTo(1 * g.MM)
+			desired = desired.NormalizedTo(1 * g.MM)
+
+			sin += current.Cross(desired).Dot(axis)
+			cos += current.Dot(desired) * axis.Length()
+		}
+
+		if sin != 0 || cos != 0 {
+			// hinges rotate clockwise around the axis
+			hinge.Angle = -g.Atan2(sin, cos)
+			hinge.Clamp()
+		}
+
+		transform = transform.Mul(fk.Link(hinge))
+	}
+}
+
+// project removes the component along axis from v.
+func project(v, axis g.Vec) g.Vec {
+	return v.Sub(axis.Scale((v.Dot(axis) / axis.Length2()).Float32()))
+}
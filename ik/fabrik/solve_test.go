@@ -0,0 +1,90 @@
+package fabrik_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/fk"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/ik/fabrik"
+	"github.com/egonelbre/hexapod/ik/legik"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+func TestSolveLegAgainstLegIK(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	body := adeept.ZeroPose()
+	body.Orient = g.Orient{Yaw: 0.2, Pitch: 0.1, Roll: -0.1}
+
+	const N = 100
+	for _, leg := range body.Legs() {
+		tested, solved := 0, 0
+		for tested < N {
+			leg.Coxa.Angle = (rng.Float32()*2 - 1) * g.Tau / 6
+			leg.Femur.Angle = (rng.Float32()*2 - 1) * g.Tau / 6
+			leg.Tibia.Angle = rng.Float32() * g.Tau / 5
+			target := fk.Effector(body, leg)
+
+			leg.Coxa.Angle, leg.Femur.Angle, leg.Tibia.Angle = 0, 0, 0
+			if !legik.SolveLeg(body, leg, target).Solved() {
+				continue
+			}
+			analytical := fk.Effector(body, leg)
+			tested++
+
+			leg.Coxa.Angle, leg.Femur.Angle, leg.Tibia.Angle = 0, 0, 0
+			r := fabrik.SolveLeg(body, leg, target, nil)
+			for _, hinge := range leg.Hinges() {
+				if !hinge.InBounds() {
+					t.Errorf("%s: hinge out of bounds %v", leg.Name, hinge.Angle)
+				}
+			}
+			if !r.Solved {
+				continue
+			}
+			solved++
+
+			if d := fk.Effector(body, leg).Distance(analytical); d > 0.2*g.MM {
+				t.Errorf("%s: differs from legik by %.3fmm", leg.Name, d.Millimeters())
+			}
+		}
+
+		if solved < N*9/10 {
+			t.Errorf("%s: solved %d out of %d", leg.Name, solved, N)
+		}
+	}
+}
+
+func TestSolve4DOF(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	hinges := []*pose.Hinge{
+		{Axis: pose.Y, Length: 12 * g.MM, Range: pose.HingeRange{Min: -g.Tau / 4, Max: g.Tau / 4}},
+		{Axis: pose.Z, Length: 38 * g.MM, Range: pose.HingeRange{Min: -g.Tau / 4, Max: g.Tau / 4}},
+		{Axis: pose.Z, Length: 30 * g.MM, Range: pose.HingeRange{Min: -g.Tau / 4, Max: g.Tau / 4}},
+		{Axis: pose.Z, Length: 25 * g.MM, Range: pose.HingeRange{Min: -g.Tau / 4, Max: g.Tau / 4}},
+	}
+	root := g.Translate(0, 50*g.MM, 0)
+
+	const N = 100
+	solved := 0
+	for i := 0; i < N; i++ {
+		for _, hinge := range hinges {
+			hinge.Angle = (rng.Float32()*2 - 1) * g.Tau / 6
+		}
+		target := fk.Chain(root, hinges)
+
+		for _, hinge := range hinges {
+			hinge.Angle = 0
+		}
+		if fabrik.Solve(root, hinges, target, nil).Solved {
+			solved++
+		}
+	}
+
+	if solved < N*8/10 {
+		t.Errorf("solved %d out of %d", solved, N)
+	}
+}
@@ -0,0 +1,20 @@
+// package ik contains the types shared by the iterative IK solvers
+package ik
+
+import "github.com/egonelbre/hexapod/g"
+
+// Options configures an iterative solver.
+type Options struct {
+	// Iterations is the maximum number of iterations.
+	Iterations int
+	// Tolerance is the acceptable distance to the target.
+	Tolerance g.Length
+}
+
+// Result describes how well the solver reached the target.
+type Result struct {
+	Solved     bool
+	Iterations int
+	// Residual is the distance between the end effector and the target.
+	Residual g.Length
+}
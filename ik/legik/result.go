@@ -0,0 +1,66 @@
+package legik
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+// Result describes how well SolveLeg managed to reach the target.
+type Result struct {
+	Failure Failure
+	// Knee is the solution that was used, either pose.KneeUp or pose.KneeDown.
+	Knee pose.Knee
+	// Residual is the distance between the end effector and the target.
+	Residual g.Length
+	// Projection is the distance a planted target was moved along the ground
+	// to make it reachable. It is zero when the target was not moved.
+	Projection g.Length
+	// Ideal contains hinge angles before clamping,
+	// in the same order as pose.Leg.Hinges().
+	Ideal [3]g.Radians
+}
+
+// Solved returns whether the target was reached exactly.
+func (result Result) Solved() bool { return result.Failure == 0 }
+
+// OutOfReach returns whether the target is outside of the leg length.
+func (result Result) OutOfReach() bool { return result.Failure&(TooFar|TooClose) != 0 }
+
+func (result Result) String() string {
+	if result.Solved() {
+		return ""
+	}
+	return fmt.Sprintf("%v %.1fmm", result.Failure, result.Residual.Millimeters())
+}
+
+// Failure describes why IK could not reach the target.
+type Failure byte
+
+const (
+	TooFar Failure = 1 << iota
+	TooClose
+	CoxaClamped
+	FemurClamped
+	TibiaClamped
+)
+
+var failureNames = []string{
+	"too far",
+	"too close",
+	"coxa clamped",
+	"femur clamped",
+	"tibia clamped",
+}
+
+func (failure Failure) String() string {
+	var names []string
+	for i, name := range failureNames {
+		if failure&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -2,44 +2,228 @@
 package legik
 
 import (
+	"github.com/egonelbre/hexapod/fk"
 	"github.com/egonelbre/hexapod/g"
 	"github.com/egonelbre/hexapod/pose"
+	"github.com/egonelbre/hexapod/terrain"
 )
 
 // Assumes:
-//   1. Coxa is parallel to the body
-//   2. There is no offset between Coxa direction and End Effector
+//   1. Coxa rotates around the body Y axis
+//   2. Femur and Tibia rotate around the Coxa Z axis
+//
+// Hinge offsets are allowed, e.g. a vertical drop between Coxa and Femur
+// or a lateral offset of the End Effector.
 
-func Solve(body *pose.Body) {
+// Solve solves all legs of the body and returns results
+// in the same order as body.Legs().
+func Solve(body *pose.Body) []Result {
+	results := make([]Result, 0, 6)
 	for _, leg := range body.Legs() {
-		leg.IK.Solved = SolveLeg(body, leg, leg.IK.Target)
+		result := SolveLeg(body, leg, leg.IK.Target)
+		leg.IK.Solved = result.Solved()
+		leg.IK.Debug = result.String()
+		results = append(results, result)
 	}
+	return results
 }
 
-func SolveLeg(body *pose.Body, leg *pose.Leg, worldTarget g.Vec) bool {
-	// TODO: handle body rotation
-
-	// Calculate Coxa.Angle by looking in Body-Space top-down
-
-	legTarget := g.Identity().
+// GroundToLeg returns the transform from body ground space into leg space,
+// the inverse of fk.LegSpace.
+//
+// Leg space has the origin at the Coxa hinge and axes aligned with the body.
+func GroundToLeg(body *pose.Body, leg *pose.Leg) g.Mat {
+	return g.Identity().
 		Mul(g.Translate(-leg.Offset.X, -leg.Offset.Y, -leg.Offset.Z)).
 		Mul(body.Orient.InvMat()).
-		Mul(g.Translate(-body.Origin.X, -body.Origin.Y, -body.Origin.Z)).
-		Transform(worldTarget)
+		Mul(g.Translate(-body.Origin.X, -body.Origin.Y, -body.Origin.Z))
+}
+
+// SolveLeg solves leg hinge angles to reach worldTarget using leg.Knee solution.
+//
+// When a planted target cannot be reached, SolveLeg moves the target to the
+// nearest reachable point on the ground, so that the foot stays planted.
+func SolveLeg(body *pose.Body, leg *pose.Leg, worldTarget g.Vec) Result {
+	result := solveKnee(body, leg, worldTarget)
+	if !result.Solved() && terrain.Planted(body.Ground(), worldTarget) {
+		if planted, ok := solvePlanted(body, leg, worldTarget, result); ok {
+			return planted
+		}
+		result = solveKnee(body, leg, worldTarget)
+	}
+	return result
+}
+
+// Reachable checks whether the leg can reach worldTarget exactly with
+// hinges in Range. The leg itself is not modified.
+func Reachable(body *pose.Body, leg *pose.Leg, worldTarget g.Vec) bool {
+	test := *leg
+	return solveKnee(body, &test, worldTarget).Solved()
+}
 
-	// bodyTarget := body.Orient.InvMat().Transform(worldTarget.Sub(body.Origin))
-	// legTarget := bodyTarget.Sub(leg.Offset)
+// solveKnee solves leg using the knee configuration.
+func solveKnee(body *pose.Body, leg *pose.Leg, worldTarget g.Vec) Result {
+	switch leg.Knee {
+	case pose.KneeDown:
+		return solveLeg(body, leg, worldTarget, pose.KneeDown)
+	case pose.KneeAuto:
+		up := solveLeg(body, leg, worldTarget, pose.KneeUp)
+		if up.Solved() {
+			return up
+		}
+		upAngles := [3]g.Radians{leg.Coxa.Angle, leg.Femur.Angle, leg.Tibia.Angle}
 
-	coxaAngleRelativeToForward := -g.Atan2(legTarget.X, legTarget.Z)
-	leg.Coxa.Angle = g.Radians(coxaAngleRelativeToForward) - leg.Coxa.Zero + g.Tau/4
+		down := solveLeg(body, leg, worldTarget, pose.KneeDown)
+		if down.Solved() || down.Residual < up.Residual {
+			return down
+		}
 
-	if leg.Coxa.Angle > g.Tau/2 {
-		leg.Coxa.Angle = leg.Coxa.Angle - g.Tau
+		leg.Coxa.Angle, leg.Femur.Angle, leg.Tibia.Angle = upAngles[0], upAngles[1], upAngles[2]
+		return up
+	default:
+		return solveLeg(body, leg, worldTarget, pose.KneeUp)
 	}
+}
+
+func solveLeg(body *pose.Body, leg *pose.Leg, worldTarget g.Vec, knee pose.Knee) Result {
+	result := Result{Knee: knee}
+
+	// Body rotation is fully handled by moving the target into leg space,
+	// all the hinges are relative to the body from there on.
+	legTarget := GroundToLeg(body, leg).Transform(worldTarget)
+
+	// Calculate Coxa.Angle by looking in Leg-Space top-down
+	coxaAngle := coxaDirection(leg, legTarget)
+	result.Ideal[0] = normalizeAngle(coxaAngle - leg.Coxa.Zero)
+	result.Ideal[1], result.Ideal[2], result.Failure = solvePlanar(leg, legTarget, coxaAngle, knee)
+
+	leg.Coxa.Angle = result.Ideal[0]
+	leg.Femur.Angle = result.Ideal[1]
+	leg.Tibia.Angle = result.Ideal[2]
 	if leg.Coxa.Clamp() {
-		coxaAngleRelativeToForward = coxaAngleRelativeToForward + leg.Coxa.Zero - g.Tau/4
+		// the target is outside of the coxa plane,
+		// find the best solution within the plane
+		leg.Femur.Angle, leg.Tibia.Angle, result.Failure = solvePlanar(leg, legTarget, leg.Coxa.Zero+leg.Coxa.Angle, knee)
+		result.Failure |= CoxaClamped
 	}
 
+	if leg.Tibia.Clamp() {
+		result.Failure |= TibiaClamped
+	}
+	if leg.Femur.Clamp() {
+		result.Failure |= FemurClamped
+	}
+
+	result.Residual = fk.Effector(body, leg).Distance(worldTarget)
+	return result
+}
+
+// solvePlanted searches for a reachable point on the ground for a planted
+// target. The search is done along the ground line from the leg origin in
+// the direction of the target or the clamped coxa, hence the result is the
+// nearest point on that line, not on the whole ground.
+//
+// The line is sampled outwards from the target in coarse steps and the
+// first reachable sample is refined by bisection.
+func solvePlanted(body *pose.Body, leg *pose.Leg, worldTarget g.Vec, failed Result) (Result, bool) {
+	const samples = 16
+	ground := body.Ground()
+
+	origin := fk.LegSpace(body, leg).Transform(g.Vec{})
+	origin.Y = worldTarget.Y
+
+	var reach g.Length
+	for _, hinge := range leg.Hinges() {
+		reach += hinge.Length
+	}
+	coarse := reach / samples
+
+	direction := worldTarget.Sub(origin)
+	if failed.Failure&CoxaClamped != 0 || direction.Length() < 1*g.MM {
+		joints := fk.SolveLeg(body, leg).Joints
+		direction = joints[1].Sub(joints[0])
+		direction.Y = 0
+	}
+	if direction.Length() < 0.01*g.MM {
+		return failed, false
+	}
+	direction = direction.NormalizedTo(1)
+
+	at := func(s g.Length) g.Vec {
+		p := origin.Add(direction.Scale(s.Float32()))
+		p.Y = ground.Height(p.X, p.Z)
+		return p
+	}
+	reachable := func(s g.Length) bool { return solveKnee(body, leg, at(s)).Solved() }
+
+	// find the closest reachable sample
+	desired := min(max(worldTarget.Sub(origin).Dot(direction), 0), reach)
+	best, found := desired, false
+	for k := 0; k <= samples && !found; k++ {
+		offset := coarse.Scale(float32(k))
+		for _, s := range []g.Length{desired - offset, desired + offset} {
+			if s >= 0 && s <= reach && reachable(s) {
+				best, found = s, true
+				break
+			}
+		}
+	}
+	if !found {
+		return failed, false
+	}
+
+	// refine towards the desired point
+	if best != desired {
+		unreachable := best + coarse
+		if desired < best {
+			unreachable = best - coarse
+		}
+		for i := 0; i < 10; i++ {
+			mid := (best + unreachable) / 2
+			if reachable(mid) {
+				best = mid
+			} else {
+				unreachable = mid
+			}
+		}
+	}
+
+	planted := at(best)
+	result := solveKnee(body, leg, planted)
+	result.Failure = failed.Failure
+	result.Ideal = failed.Ideal
+	result.Residual = fk.Effector(body, leg).Distance(worldTarget)
+	result.Projection = planted.Distance(worldTarget)
+	return result, true
+}
+
+// coxaDirection returns the Coxa rotation that turns the leg towards the target.
+//
+// Lateral offsets of the hinges don't depend on Femur and Tibia rotation,
+// hence the leg plane needs to be turned such that the target is
+// offset by the same amount.
+func coxaDirection(leg *pose.Leg, legTarget g.Vec) g.Radians {
+	direction := g.Atan2(legTarget.Z, legTarget.X)
+
+	lateral := leg.Coxa.Offset.Z + leg.Femur.Offset.Z + leg.Tibia.Offset.Z
+	if lateral == 0 {
+		return direction
+	}
+
+	distance := g.Vec{X: legTarget.X, Z: legTarget.Z}.Length()
+	if distance <= g.Abs(lateral) {
+		if lateral > 0 {
+			return direction - g.Tau/4
+		}
+		return direction + g.Tau/4
+	}
+
+	reach := (distance*distance - lateral*lateral).Sqrt()
+	return direction - g.Atan2(lateral, reach)
+}
+
+// solvePlanar solves Femur and Tibia angles when Coxa is rotated to coxaAngle.
+func solvePlanar(leg *pose.Leg, legTarget g.Vec, coxaAngle g.Radians, knee pose.Knee) (femur, tibia g.Radians, failure Failure) {
 	//  +---------------------------------------------------------+
 	//  | in Coxa coordinate space (leg side-view)                |
 	//  |                                  .                      |
@@ -55,68 +239,84 @@ func SolveLeg(body *pose.Body, leg *pose.Leg, worldTarget g.Vec) bool {
 	//  | |       Coxa.Length        ^--- Femur.Angle             |
 	//  | +-----> x                                               |
 	//  +---------------------------------------------------------+
+	//
+	// Angles below are measured counter-clockwise from x, whereas
+	// hinge rotation around Z is clockwise.
 
 	// move into Coxa space
-	target := g.RotateY(-coxaAngleRelativeToForward).Transform(legTarget)
+	target := g.RotateY(-coxaAngle).Transform(legTarget)
 
 	// move 0,0,0 to Femur root
-	target.X = 0
-	target.Z -= leg.Coxa.Length
+	target = target.Sub(leg.Coxa.End())
+	target.Z = 0
 
-	femurLength2 := leg.Femur.Length * leg.Femur.Length
-	tibiaLength2 := leg.Tibia.Length * leg.Tibia.Length
+	// Femur and Tibia links, including the offsets
+	femurEnd, tibiaEnd := leg.Femur.End(), leg.Tibia.End()
+	femurLength := g.Vec{X: femurEnd.X, Y: femurEnd.Y}.Length()
+	tibiaLength := g.Vec{X: tibiaEnd.X, Y: tibiaEnd.Y}.Length()
+	femurSkew := g.Atan2(femurEnd.Y, femurEnd.X)
+	tibiaSkew := g.Atan2(tibiaEnd.Y, tibiaEnd.X)
+
+	femurLength2 := femurLength * femurLength
+	tibiaLength2 := tibiaLength * tibiaLength
 	targetDistance2 := target.Length2()
 	targetDistance := targetDistance2.Sqrt()
 
-	footToTargetAngle := g.Atan2(-target.Y, target.Z)
+	targetDirection := g.Atan2(target.Y, target.X)
 
-	if leg.Femur.Length+leg.Tibia.Length < targetDistance {
+	var femurDirection, tibiaDirection g.Radians
+	if femurLength+tibiaLength < targetDistance {
 		// too far away, straighten leg and point at
-		leg.Femur.Angle = footToTargetAngle
-		leg.Tibia.Angle = 0
-		if pose.VectorPlanted(worldTarget) {
-			/*
-				// if the target should be planted, try to plant foot
-				footLength := leg.Femur.Length + leg.Tibia.Length
-				if footLength > g.Abs(coxaOrigin.Y) {
-					leg.Femur.Angle = g.Asin(coxaOrigin.Y.Float32() / footLength.Float32())
-				} else {
-					leg.Femur.Angle = g.Tau / 4
-				}
-			*/
+		femurDirection = targetDirection
+		tibiaDirection = targetDirection
+		failure = TooFar
+	} else {
+		if targetDistance < g.Abs(femurLength-tibiaLength) {
+			failure = TooClose
 		}
-		leg.Femur.Clamp()
-		return false
-	}
 
-	femurInternalAngle := g.Acos((femurLength2 + targetDistance2 - tibiaLength2).Float32() / (2 * leg.Femur.Length * targetDistance).Float32())
-	tibiaInternalAngle := g.Acos((femurLength2 + tibiaLength2 - targetDistance2).Float32() / (2 * leg.Femur.Length * leg.Tibia.Length).Float32())
+		var femurInternalAngle g.Radians
+		if targetDistance > 0 {
+			femurInternalAngle = acos((femurLength2 + targetDistance2 - tibiaLength2).Float32() / (2 * femurLength * targetDistance).Float32())
+		}
 
-	leg.Femur.Angle = footToTargetAngle - femurInternalAngle
-	leg.Tibia.Angle = g.Tau/2 - tibiaInternalAngle
+		if knee == pose.KneeDown {
+			femurDirection = targetDirection - femurInternalAngle
+		} else {
+			femurDirection = targetDirection + femurInternalAngle
+		}
 
-	if false /* leg flipped */ {
-		leg.Femur.Angle = footToTargetAngle + femurInternalAngle
-		leg.Tibia.Angle = -(g.Tau/2 - tibiaInternalAngle)
+		femurSn, femurCs := g.Sincos(femurDirection)
+		tibiaDirection = g.Atan2(
+			target.Y-femurLength.Scale(femurSn),
+			target.X-femurLength.Scale(femurCs))
 	}
 
-	if leg.Tibia.Clamp() {
-		if pose.VectorPlanted(worldTarget) {
-			/*
-				footLength2 := femurLength2 + tibiaLength2 - g.Length((2*leg.Femur.Length*leg.Tibia.Length).Float32()*g.Cos(leg.Tibia.Angle))
-				footLength := footLength2.Sqrt()
+	femur = femurSkew - femurDirection
+	tibia = tibiaSkew - tibiaDirection - femur
 
-				footInternalAngle := g.Asin(leg.Tibia.Length.Float32() * g.Sin(leg.Tibia.Angle) / footLength.Float32())
-				footAngle := g.Asin(coxaOrigin.Y.Float32() / footLength.Float32())
+	femur = normalizeAngle(femur - leg.Femur.Zero)
+	tibia = normalizeAngle(tibia - leg.Tibia.Zero)
+	return femur, tibia, failure
+}
 
-				leg.Femur.Angle = footAngle - footInternalAngle
-			*/
-		}
-		leg.Femur.Clamp()
-		return false
+// normalizeAngle wraps angle into range (-Tau/2, Tau/2].
+func normalizeAngle(angle g.Radians) g.Radians {
+	for angle > g.Tau/2 {
+		angle -= g.Tau
 	}
-	if leg.Femur.Clamp() {
-		return false
+	for angle <= -g.Tau/2 {
+		angle += g.Tau
+	}
+	return angle
+}
+
+// acos is g.Acos that tolerates rounding errors outside of [-1, 1].
+func acos(v float32) g.Radians {
+	if v > 1 {
+		v = 1
+	} else if v < -1 {
+		v = -1
 	}
-	return true
+	return g.Acos(v)
 }
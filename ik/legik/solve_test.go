@@ -0,0 +1,249 @@
+package legik_test
+
+import (
+	"testing"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/fk"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/ik/legik"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+const tolerance = 0.1 * g.MM
+
+func sweep(from, to g.Radians, n int) []g.Radians {
+	xs := make([]g.Radians, n)
+	for i := range xs {
+		xs[i] = from + (to-from)*g.Radians(i)/g.Radians(n-1)
+	}
+	return xs
+}
+
+// forEachPose calls fn with reachable targets for all legs and various
+// body orientations, where tibia angles are taken from tibias.
+func forEachPose(body *pose.Body, tibias []g.Radians, fn func(leg *pose.Leg, target g.Vec)) {
+	for _, yaw := range sweep(-g.Tau/2, g.Tau/2, 5) {
+		for _, pitch := range sweep(-g.Tau/8, g.Tau/8, 3) {
+			for _, roll := range sweep(-g.Tau/8, g.Tau/8, 3) {
+				body.Orient = g.Orient{Yaw: yaw, Pitch: pitch, Roll: roll}
+
+				for _, leg := range body.Legs() {
+					for _, coxa := range sweep(-g.Tau/6, g.Tau/6, 5) {
+						for _, femur := range sweep(-g.Tau/6, g.Tau/6, 5) {
+							for _, tibia := range tibias {
+								// skip feet that end up behind the coxa axis
+								reach := leg.Coxa.Length +
+									leg.Femur.Length.Scale(g.Cos(femur)) +
+									leg.Tibia.Length.Scale(g.Cos(femur+tibia))
+								if reach < 5*g.MM {
+									continue
+								}
+
+								leg.Coxa.Angle = coxa
+								leg.Femur.Angle = femur
+								leg.Tibia.Angle = tibia
+								target := fk.Effector(body, leg)
+
+								leg.Coxa.Angle, leg.Femur.Angle, leg.Tibia.Angle = 0, 0, 0
+								fn(leg, target)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestSolveLegOrient(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Origin = g.Vec{X: 7 * g.MM, Y: 60 * g.MM, Z: -5 * g.MM}
+
+	tested := 0
+	forEachPose(body, sweep(g.Tau/36, g.Tau/5, 3), func(leg *pose.Leg, target g.Vec) {
+		if r := legik.SolveLeg(body, leg, target); !r.Solved() {
+			t.Errorf("%v %s: failed to solve %v: %v", body.Orient, leg.Name, target, r)
+			return
+		}
+
+		tested++
+		if d := fk.Effector(body, leg).Distance(target); d > tolerance {
+			t.Errorf("%v %s: error %.3fmm", body.Orient, leg.Name, d.Millimeters())
+		}
+	})
+
+	if tested == 0 {
+		t.Fatal("no cases tested")
+	}
+}
+
+func TestSolveLegKneeDown(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Origin = g.Vec{X: -3 * g.MM, Y: 40 * g.MM, Z: 2 * g.MM}
+
+	tibias := sweep(-g.Tau/5, -g.Tau/36, 3)
+	for _, knee := range []pose.Knee{pose.KneeDown, pose.KneeAuto} {
+		for _, leg := range body.Legs() {
+			leg.Knee = knee
+		}
+
+		tested, flipped := 0, 0
+		forEachPose(body, tibias, func(leg *pose.Leg, target g.Vec) {
+			r := legik.SolveLeg(body, leg, target)
+			if !r.Solved() {
+				t.Errorf("%v %s: failed to solve %v: %v", body.Orient, leg.Name, target, r)
+				return
+			}
+			if r.Knee == pose.KneeDown {
+				flipped++
+			}
+
+			tested++
+			if d := fk.Effector(body, leg).Distance(target); d > tolerance {
+				t.Errorf("%v %s: error %.3fmm", body.Orient, leg.Name, d.Millimeters())
+			}
+		})
+
+		if tested == 0 || flipped == 0 {
+			t.Fatalf("knee %v: tested %d, flipped %d", knee, tested, flipped)
+		}
+	}
+}
+
+func TestSolveLegOffsets(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Origin = g.Vec{X: 5 * g.MM, Y: 55 * g.MM, Z: 3 * g.MM}
+	for _, leg := range body.Legs() {
+		leg.Coxa.Offset = g.Vec{Y: -6 * g.MM, Z: 4 * g.MM}
+		leg.Femur.Offset = g.Vec{Y: 5 * g.MM, Z: -2 * g.MM}
+		leg.Tibia.Offset = g.Vec{X: 3 * g.MM, Y: -8 * g.MM, Z: 5 * g.MM}
+	}
+
+	for _, knee := range []pose.Knee{pose.KneeUp, pose.KneeDown} {
+		tibias := sweep(g.Tau/36, g.Tau/5, 3)
+		if knee == pose.KneeDown {
+			tibias = sweep(-g.Tau/5, -g.Tau/36, 3)
+		}
+		for _, leg := range body.Legs() {
+			leg.Knee = knee
+		}
+
+		tested := 0
+		forEachPose(body, tibias, func(leg *pose.Leg, target g.Vec) {
+			r := legik.SolveLeg(body, leg, target)
+			if !r.Solved() {
+				t.Errorf("%v %s: failed to solve %v: %v", body.Orient, leg.Name, target, r)
+				return
+			}
+
+			tested++
+			if d := fk.Effector(body, leg).Distance(target); d > tolerance {
+				t.Errorf("%v %s: error %.3fmm", body.Orient, leg.Name, d.Millimeters())
+			}
+		})
+
+		if tested == 0 {
+			t.Fatalf("knee %v: no cases tested", knee)
+		}
+	}
+}
+
+func TestSolveLegUnreachable(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Orient = g.Orient{Yaw: g.Tau / 8, Pitch: g.Tau / 16, Roll: -g.Tau / 16}
+
+	leg := &body.Leg.RM
+	target := fk.Effector(body, leg).Add(g.Vec{Z: 500 * g.MM})
+	r := legik.SolveLeg(body, leg, target)
+	if r.Solved() {
+		t.Errorf("solved unreachable target %v", target)
+	}
+	if !r.OutOfReach() || r.Failure&legik.TooFar == 0 {
+		t.Errorf("got %v; exp %v", r.Failure, legik.TooFar)
+	}
+	if r.Residual < 300*g.MM {
+		t.Errorf("residual %.3fmm too small", r.Residual.Millimeters())
+	}
+	for _, hinge := range leg.Hinges() {
+		if !hinge.InBounds() {
+			t.Errorf("hinge out of bounds: %v", hinge.Angle)
+		}
+	}
+}
+
+func TestSolveLegCoxaClamped(t *testing.T) {
+	body := adeept.ZeroPose()
+	leg := &body.Leg.RM
+
+	// forward-left of the leg origin, outside of the coxa range
+	ideal := -3 * g.Tau / 8
+	target := body.Origin.Add(leg.Offset).Add(g.Vec{
+		X: (60 * g.MM).Scale(g.Cos(ideal)),
+		Y: -body.Origin.Y - leg.Offset.Y,
+		Z: (60 * g.MM).Scale(g.Sin(ideal)),
+	})
+
+	r := legik.SolveLeg(body, leg, target)
+	if r.Failure&legik.CoxaClamped == 0 {
+		t.Errorf("got %v; exp %v", r.Failure, legik.CoxaClamped)
+	}
+	if r.OutOfReach() {
+		t.Errorf("got %v; exp target in reach", r.Failure)
+	}
+	if exp := ideal - leg.Coxa.Zero + g.Tau; g.Abs(r.Ideal[0]-exp) > 0.001 {
+		t.Errorf("ideal coxa got %v; exp %v", r.Ideal[0], exp)
+	}
+	if !leg.Coxa.InBounds() {
+		t.Errorf("coxa out of bounds: %v", leg.Coxa.Angle)
+	}
+	if d := fk.Effector(body, leg).Distance(target); g.Abs(d-r.Residual) > tolerance {
+		t.Errorf("residual got %.3fmm; exp %.3fmm", r.Residual.Millimeters(), d.Millimeters())
+	}
+}
+
+func TestSolveLegPlanted(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Origin.Y = 45 * g.MM
+	body.Orient = g.Orient{Pitch: g.Tau / 64, Roll: -g.Tau / 96}
+
+	type Case struct {
+		Name     string
+		Distance g.Length
+		Failure  legik.Failure
+	}
+	cases := []Case{
+		{"far", 200 * g.MM, legik.TooFar},
+		{"under", 20 * g.MM, legik.TibiaClamped},
+	}
+
+	for _, c := range cases {
+		for _, leg := range body.Legs() {
+			target := fk.LegSpace(body, leg).Transform(g.Vec{})
+			target = target.Add(leg.Offset.NormalizedTo(c.Distance))
+			target.Y = 0
+
+			r := legik.SolveLeg(body, leg, target)
+			if r.Solved() || r.Failure&c.Failure == 0 {
+				t.Errorf("%s %s: got %v; exp %v", c.Name, leg.Name, r.Failure, c.Failure)
+			}
+			if r.Projection <= 0 {
+				t.Errorf("%s %s: target was not projected", c.Name, leg.Name)
+			}
+
+			foot := fk.Effector(body, leg)
+			if !pose.VectorPlanted(foot) {
+				t.Errorf("%s %s: foot not planted %v", c.Name, leg.Name, foot)
+			}
+			if d := foot.Distance(target); g.Abs(d-r.Residual) > tolerance || g.Abs(d-r.Projection) > 1*g.MM {
+				t.Errorf("%s %s: distance %.3fmm, residual %.3fmm, projection %.3fmm", c.Name, leg.Name,
+					d.Millimeters(), r.Residual.Millimeters(), r.Projection.Millimeters())
+			}
+			for _, hinge := range leg.Hinges() {
+				if !hinge.InBounds() {
+					t.Errorf("%s %s: hinge out of bounds: %v", c.Name, leg.Name, hinge.Angle)
+				}
+			}
+		}
+	}
+}
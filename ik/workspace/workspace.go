@@ -0,0 +1,133 @@
+// package workspace samples where a leg can reach
+//
+// See legik.Reachable for checking a single target.
+package workspace
+
+import (
+	"fmt"
+
+	"github.com/egonelbre/hexapod/fk"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/ik/legik"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+// PointCloud samples the reachable workspace of the leg by stepping
+// every hinge through its Range in n steps.
+//
+// With n = 1 only the middle of the ranges is sampled, n < 1 panics.
+func PointCloud(body *pose.Body, leg *pose.Leg, n int) []g.Vec {
+	checkSamples(n)
+
+	test := *leg
+	hinges := test.Hinges()
+
+	points := []g.Vec{}
+	var sample func(k int)
+	sample = func(k int) {
+		if k == len(hinges) {
+			points = append(points, fk.Effector(body, &test))
+			return
+		}
+		hinge := hinges[k]
+		for i := 0; i < n; i++ {
+			hinge.Angle = sweep(hinge.Range, i, n)
+			sample(k + 1)
+		}
+	}
+	sample(0)
+
+	return points
+}
+
+// Footprint calculates the area where the leg can touch the ground at groundY.
+//
+// The footprint is sampled in n directions within the Coxa range, from the
+// ground point under the leg origin. The polygon is ordered along the far
+// edge and then back along the near edge.
+//
+// With n = 1 only the middle of the Coxa range is sampled, n < 1 panics.
+func Footprint(body *pose.Body, leg *pose.Leg, groundY g.Length, n int) g.Polygon {
+	const step = 1 * g.MM
+	checkSamples(n)
+
+	origin := fk.LegSpace(body, leg).Transform(g.Vec{})
+	origin.Y = groundY
+
+	var reach g.Length
+	for _, hinge := range leg.Hinges() {
+		reach += hinge.End().Length()
+	}
+
+	orient := body.Orient.Mat()
+
+	var far, near []g.Vec
+	for i := 0; i < n; i++ {
+		coxa := sweep(leg.Coxa.Range, i, n)
+
+		direction := orient.Transform(fk.Rotate(pose.Y, leg.Coxa.Zero+coxa).Transform(g.Vec{X: g.MM}))
+		direction.Y = 0
+		if direction.Length() == 0 {
+			continue
+		}
+		direction = direction.NormalizedTo(1)
+
+		at := func(s g.Length) g.Vec { return origin.Add(direction.Scale(s.Float32())) }
+		reachable := func(s g.Length) bool { return legik.Reachable(body, leg, at(s)) }
+
+		// find the reachable interval
+		first, last := g.Length(-1), g.Length(-1)
+		for s := g.Length(0); s <= reach; s += step {
+			if reachable(s) {
+				if first < 0 {
+					first = s
+				}
+				last = s
+			}
+		}
+		if first < 0 {
+			continue
+		}
+
+		near = append(near, at(refine(reachable, first, first-step)))
+		far = append(far, at(refine(reachable, last, last+step)))
+	}
+
+	polygon := g.Polygon(far)
+	for i := len(near) - 1; i >= 0; i-- {
+		polygon = append(polygon, near[i])
+	}
+	return polygon
+}
+
+// refine finds the boundary between reachable inside and unreachable outside.
+func refine(reachable func(s g.Length) bool, inside, outside g.Length) g.Length {
+	if outside < 0 {
+		return inside
+	}
+	for i := 0; i < 10; i++ {
+		mid := (inside + outside) / 2
+		if reachable(mid) {
+			inside = mid
+		} else {
+			outside = mid
+		}
+	}
+	return inside
+}
+
+// sweep returns the i-th of n angles evenly spread over the range,
+// a single angle is in the middle.
+func sweep(rng pose.HingeRange, i, n int) g.Radians {
+	if n == 1 {
+		return (rng.Min + rng.Max) / 2
+	}
+	return rng.Min + (rng.Max-rng.Min)*g.Radians(i)/g.Radians(n-1)
+}
+
+// checkSamples panics when n is not a valid number of samples.
+func checkSamples(n int) {
+	if n < 1 {
+		panic(fmt.Sprintf("workspace: invalid number of samples %d", n))
+	}
+}
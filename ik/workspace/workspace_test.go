@@ -0,0 +1,105 @@
+package workspace_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/fk"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/ik/legik"
+	"github.com/egonelbre/hexapod/ik/workspace"
+)
+
+func TestPointCloud(t *testing.T) {
+	body := adeept.ZeroPose()
+	leg := &body.Leg.RF
+	leg.Coxa.Angle = 0.1
+
+	points := workspace.PointCloud(body, leg, 5)
+	if len(points) != 5*5*5 {
+		t.Fatalf("got %d points; exp %d", len(points), 5*5*5)
+	}
+	if leg.Coxa.Angle != 0.1 {
+		t.Errorf("leg was modified")
+	}
+
+	origin := fk.LegSpace(body, leg).Transform(g.Vec{})
+	reach := leg.Coxa.Length + leg.Femur.Length + leg.Tibia.Length
+	for _, p := range points {
+		if d := p.Distance(origin); d > reach+0.1*g.MM {
+			t.Errorf("%v too far %.3fmm", p, d.Millimeters())
+		}
+	}
+}
+
+func TestFootprint(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Origin.Y = body.Size.Y
+
+	for _, leg := range body.Legs() {
+		footprint := workspace.Footprint(body, leg, 0, 16)
+		if len(footprint) < 3 {
+			t.Fatalf("%s: footprint too small %v", leg.Name, footprint)
+		}
+
+		// standing target used by the simulator controls
+		stand := leg.Offset.Add(leg.Offset.NormalizedTo(70 * g.MM))
+		stand.Y = 0
+		if !footprint.Contains(stand) {
+			t.Errorf("%s: footprint does not contain %v", leg.Name, stand)
+		}
+		if !legik.Reachable(body, leg, stand) {
+			t.Errorf("%s: %v not reachable", leg.Name, stand)
+		}
+
+		far := leg.Offset.Add(leg.Offset.NormalizedTo(200 * g.MM))
+		far.Y = 0
+		if footprint.Contains(far) || legik.Reachable(body, leg, far) {
+			t.Errorf("%s: %v should not be reachable", leg.Name, far)
+		}
+
+		for _, p := range footprint {
+			if !legik.Reachable(body, leg, p) {
+				t.Errorf("%s: footprint point %v not reachable", leg.Name, p)
+			}
+		}
+	}
+}
+
+func TestSingleSample(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Origin.Y = body.Size.Y
+	leg := &body.Leg.RF
+
+	points := workspace.PointCloud(body, leg, 1)
+	if len(points) != 1 {
+		t.Fatalf("got %d points; exp 1", len(points))
+	}
+	if !finite(points[0]) {
+		t.Errorf("invalid point %v", points[0])
+	}
+
+	for _, p := range workspace.Footprint(body, leg, 0, 1) {
+		if !finite(p) {
+			t.Errorf("invalid footprint point %v", p)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for zero samples")
+		}
+	}()
+	workspace.PointCloud(body, leg, 0)
+}
+
+func finite(p g.Vec) bool {
+	for _, v := range []g.Length{p.X, p.Y, p.Z} {
+		f := float64(v)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return false
+		}
+	}
+	return true
+}
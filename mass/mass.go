@@ -0,0 +1,132 @@
+// package mass calculates mass properties of a pose.Body
+package mass
+
+import (
+	"github.com/egonelbre/hexapod/fk"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+// Properties describes the mass of the whole robot.
+type Properties struct {
+	Kilograms float32
+	// Center is the center of mass in body ground space.
+	Center g.Vec
+	// Inertia is around Center with body ground axes, in kg·m².
+	Inertia Inertia
+}
+
+// Inertia is a 3x3 inertia tensor.
+type Inertia [3][3]float32
+
+// Segment is a single mass in body ground space.
+type Segment struct {
+	Name      string
+	Kilograms float32
+	Center    g.Vec
+	// Box is the size of the segment, zero for point masses.
+	Box g.Vec
+	// Axes are the body ground space directions of the Box axes.
+	Axes [3]g.Vec
+}
+
+// Segments returns all the masses of the body in body ground space.
+//
+// The body is a solid box of body.Size, the other segments are point masses.
+func Segments(body *pose.Body) []Segment {
+	bodySpace := fk.BodySpace(body)
+	orient := body.Orient.Mat()
+
+	segments := []Segment{{
+		Name:      "Body",
+		Kilograms: body.Mass.Kilograms,
+		Center:    bodySpace.Transform(body.Mass.Center),
+		Box:       body.Size,
+		Axes: [3]g.Vec{
+			orient.Transform(g.Forward),
+			orient.Transform(g.Up),
+			orient.Transform(g.Right),
+		},
+	}, {
+		Name:      "Head",
+		Kilograms: body.Head.Mass.Kilograms,
+		Center:    bodySpace.Transform(body.Head.Offset.Add(body.Head.Mass.Center)),
+	}}
+
+	for _, leg := range body.Legs() {
+		solved := fk.SolveLeg(body, leg)
+		for i, hinge := range leg.Hinges() {
+			segments = append(segments, Segment{
+				Name:      leg.Name + hingeNames[i],
+				Kilograms: hinge.Mass.Kilograms,
+				Center:    solved.Frames[i].Transform(hinge.Mass.Center),
+			})
+		}
+	}
+
+	return segments
+}
+
+var hingeNames = [3]string{"Coxa", "Femur", "Tibia"}
+
+// Calculate calculates the mass properties of the body in its current pose.
+func Calculate(body *pose.Body) Properties {
+	return Combine(Segments(body))
+}
+
+// Combine calculates the mass properties of segments.
+func Combine(segments []Segment) Properties {
+	var props Properties
+	var moment g.Vec
+	for _, segment := range segments {
+		props.Kilograms += segment.Kilograms
+		moment = moment.Add(segment.Center.Scale(segment.Kilograms))
+	}
+	if props.Kilograms <= 0 {
+		return props
+	}
+	props.Center = moment.Scale(1 / props.Kilograms)
+
+	for _, segment := range segments {
+		// parallel axis theorem
+		r := segment.Center.Sub(props.Center)
+		x, y, z := r.X.Meters(), r.Y.Meters(), r.Z.Meters()
+		props.Inertia.add(segment.Kilograms, Inertia{
+			{y*y + z*z, -x * y, -x * z},
+			{-x * y, x*x + z*z, -y * z},
+			{-x * z, -y * z, x*x + y*y},
+		})
+
+		if segment.Box == (g.Vec{}) {
+			continue
+		}
+
+		// solid box around its own axes
+		sx, sy, sz := segment.Box.X.Meters(), segment.Box.Y.Meters(), segment.Box.Z.Meters()
+		principal := [3]float32{
+			(sy*sy + sz*sz) / 12,
+			(sx*sx + sz*sz) / 12,
+			(sx*sx + sy*sy) / 12,
+		}
+		for k, axis := range segment.Axes {
+			u := [3]float32{axis.X.Float32(), axis.Y.Float32(), axis.Z.Float32()}
+			norm := u[0]*u[0] + u[1]*u[1] + u[2]*u[2]
+			for i := range u {
+				for j := range u {
+					props.Inertia[i][j] += segment.Kilograms * principal[k] * u[i] * u[j] / norm
+				}
+			}
+		}
+	}
+
+	return props
+}
+
+// add adds the inertia scaled by s.
+func (inertia *Inertia) add(s float32, other Inertia) {
+	for i := range inertia {
+		for j := range inertia[i] {
+			inertia[i][j] += s * other[i][j]
+		}
+	}
+}
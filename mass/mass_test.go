@@ -0,0 +1,103 @@
+package mass_test
+
+import (
+	"testing"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/mass"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+func approxEqual(a, b float32) bool {
+	d := a - b
+	return -1e-4 < d && d < 1e-4
+}
+
+func TestCenter(t *testing.T) {
+	body := adeept.ZeroPose()
+
+	total := body.Mass.Kilograms + body.Head.Mass.Kilograms
+	for _, leg := range body.Legs() {
+		for _, hinge := range leg.Hinges() {
+			total += hinge.Mass.Kilograms
+		}
+	}
+
+	props := mass.Calculate(body)
+	if !approxEqual(props.Kilograms, total) {
+		t.Errorf("mass got %v; exp %v", props.Kilograms, total)
+	}
+	if g.Abs(props.Center.Z) > 0.01*g.MM || props.Center.X <= body.Origin.X {
+		t.Errorf("center got %v; exp ahead of %v", props.Center, body.Origin)
+	}
+
+	// folding the right legs moves the center to the left
+	for _, leg := range []*pose.Leg{&body.Leg.RF, &body.Leg.RM, &body.Leg.RB} {
+		leg.Tibia.Angle = g.Tau / 4
+	}
+	folded := mass.Calculate(body)
+	if folded.Center.Z >= props.Center.Z || folded.Center.Y >= props.Center.Y {
+		t.Errorf("center got %v; exp left and below of %v", folded.Center, props.Center)
+	}
+
+	// moving the body moves the center
+	body.Origin = body.Origin.Add(g.Vec{X: 10 * g.MM, Y: 5 * g.MM})
+	moved := mass.Calculate(body)
+	if d := moved.Center.Sub(folded.Center).Distance(g.Vec{X: 10 * g.MM, Y: 5 * g.MM}); d > 0.01*g.MM {
+		t.Errorf("center moved by %v", moved.Center.Sub(folded.Center))
+	}
+}
+
+func TestInertia(t *testing.T) {
+	points := mass.Combine([]mass.Segment{
+		{Kilograms: 1, Center: g.Vec{X: g.M}},
+		{Kilograms: 1, Center: g.Vec{X: -g.M}},
+	})
+	exp := mass.Inertia{{0, 0, 0}, {0, 2, 0}, {0, 0, 2}}
+	if points.Inertia != exp {
+		t.Errorf("points got %v; exp %v", points.Inertia, exp)
+	}
+
+	box := mass.Segment{
+		Kilograms: 12,
+		Box:       g.Vec{X: g.M, Y: 2 * g.M, Z: 3 * g.M},
+		Axes:      [3]g.Vec{g.Forward, g.Up, g.Right},
+	}
+	straight := mass.Combine([]mass.Segment{box})
+	exp = mass.Inertia{{13, 0, 0}, {0, 10, 0}, {0, 0, 5}}
+	if straight.Inertia != exp {
+		t.Errorf("box got %v; exp %v", straight.Inertia, exp)
+	}
+
+	// quarter turn around Y swaps X and Z
+	box.Axes = [3]g.Vec{g.Right, g.Up, g.Back}
+	turned := mass.Combine([]mass.Segment{box})
+	exp = mass.Inertia{{5, 0, 0}, {0, 10, 0}, {0, 0, 13}}
+	for i := range exp {
+		for j := range exp[i] {
+			if !approxEqual(turned.Inertia[i][j], exp[i][j]) {
+				t.Errorf("turned got %v; exp %v", turned.Inertia, exp)
+			}
+		}
+	}
+}
+
+func TestInertiaSymmetric(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Orient = g.Orient{Yaw: 0.3, Pitch: 0.2, Roll: -0.1}
+	body.Leg.RF.Coxa.Angle = 0.4
+	body.Leg.LM.Femur.Angle = -0.3
+
+	inertia := mass.Calculate(body).Inertia
+	for i := range inertia {
+		if inertia[i][i] <= 0 {
+			t.Errorf("non-positive diagonal %v", inertia)
+		}
+		for j := range inertia {
+			if !approxEqual(inertia[i][j], inertia[j][i]) {
+				t.Errorf("not symmetric %v", inertia)
+			}
+		}
+	}
+}
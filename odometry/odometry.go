@@ -0,0 +1,108 @@
+// package odometry estimates the world pose from planted feet
+package odometry
+
+import (
+	"github.com/egonelbre/hexapod/fk"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/pose"
+	"github.com/egonelbre/hexapod/terrain"
+)
+
+// Odometry integrates the motion of planted feet into a world pose.
+//
+// Feet are in body ground space, the frame used by legik. Planted feet
+// don't move in the world, hence when they move relative to the body,
+// the body has moved in the opposite direction.
+type Odometry struct {
+	// World is the pose of body ground space in world space.
+	World pose.Ground
+
+	previous []Foot
+}
+
+// Foot is a foot position in body ground space.
+type Foot struct {
+	Position g.Vec
+	Planted  bool
+}
+
+// Feet returns the feet of the body, in the same order as body.Legs().
+//
+// A foot is planted when it is meant to be planted and it is on the ground.
+func Feet(body *pose.Body) []Foot {
+	feet := make([]Foot, 0, 6)
+	ground := body.Ground()
+	for _, leg := range body.Legs() {
+		position := fk.Effector(body, leg)
+		feet = append(feet, Foot{
+			Position: position,
+			Planted:  leg.IK.Planted && terrain.Planted(ground, position),
+		})
+	}
+	return feet
+}
+
+// Update moves World by the motion of feet since the previous update.
+//
+// Only feet that were planted in both updates are used. With a single
+// such foot only the position changes, without any the pose is kept.
+func (odometry *Odometry) Update(feet []Foot) {
+	previous := odometry.previous
+	odometry.previous = append(odometry.previous[:0:0], feet...)
+	if len(previous) != len(feet) {
+		return
+	}
+
+	var before, after []g.Vec
+	for i, foot := range feet {
+		if foot.Planted && previous[i].Planted {
+			before = append(before, ground(previous[i].Position))
+			after = append(after, ground(foot.Position))
+		}
+	}
+	if len(before) == 0 {
+		return
+	}
+
+	from, to := centroid(before), centroid(after)
+
+	// rotation that best maps the current feet onto the previous
+	var sin, cos g.Length
+	if len(before) > 1 {
+		for i := range before {
+			p, q := before[i].Sub(from), after[i].Sub(to)
+			sin += q.X*p.Z - q.Z*p.X
+			cos += q.X*p.X + q.Z*p.Z
+		}
+	}
+
+	world := odometry.World
+	if sin != 0 || cos != 0 {
+		world.Yaw += g.Atan2(sin, cos)
+	}
+	anchor := odometry.World.World(from)
+	world.Position = anchor.Sub(g.RotateY(world.Yaw).Transform(to))
+	world.Position.Y = 0
+	odometry.World = world
+}
+
+// Reset sets the world pose and forgets the previous feet.
+func (odometry *Odometry) Reset(world pose.Ground) {
+	odometry.World = world
+	odometry.previous = nil
+}
+
+// ground projects p to the ground.
+func ground(p g.Vec) g.Vec {
+	p.Y = 0
+	return p
+}
+
+// centroid returns the average of points.
+func centroid(points []g.Vec) g.Vec {
+	var sum g.Vec
+	for _, p := range points {
+		sum = sum.Add(p)
+	}
+	return sum.Scale(1 / float32(len(points)))
+}
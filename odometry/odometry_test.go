@@ -0,0 +1,74 @@
+package odometry_test
+
+import (
+	"testing"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/gait"
+	"github.com/egonelbre/hexapod/ik/legik"
+	"github.com/egonelbre/hexapod/odometry"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+func TestWalk(t *testing.T) {
+	body := adeept.ZeroPose()
+	body.Origin.Y = 45 * g.MM
+	engine := gait.NewEngine(body, gait.Ripple)
+	engine.Velocity = g.Vec{X: 20 * g.MM, Z: -10 * g.MM}
+	engine.TurnRate = g.Tau / 16
+
+	start := pose.Ground{Position: g.Vec{X: 50 * g.MM, Z: 20 * g.MM}, Yaw: -g.Tau / 3}
+	expected := start
+
+	var odo odometry.Odometry
+	odo.Reset(start)
+
+	const dt = 1.0 / 60
+	for frame := 0; frame < 240; frame++ {
+		if frame > 0 {
+			expected = expected.Move(engine.CurrentVelocity, engine.CurrentTurnRate, dt)
+		}
+		engine.Update(body, dt)
+		legik.Solve(body)
+		odo.Update(odometry.Feet(body))
+	}
+
+	if d := odo.World.Position.Distance(expected.Position); d > 1*g.MM {
+		t.Errorf("position got %v; exp %v", odo.World.Position, expected.Position)
+	}
+	if d := g.Abs(pose.Turn(odo.World.Yaw, expected.Yaw)); d > 0.01 {
+		t.Errorf("yaw got %v; exp %v", odo.World.Yaw, expected.Yaw)
+	}
+}
+
+func TestUnplanted(t *testing.T) {
+	start := pose.Ground{Position: g.Vec{X: 10 * g.MM}, Yaw: g.Tau / 8}
+	feet := []odometry.Foot{
+		{Position: g.Vec{X: 100 * g.MM, Z: 100 * g.MM}, Planted: true},
+		{Position: g.Vec{X: -100 * g.MM, Z: 100 * g.MM}},
+	}
+
+	var odo odometry.Odometry
+	odo.Reset(start)
+	odo.Update(feet)
+
+	// only unplanted feet move
+	feet[0].Planted = false
+	feet[1].Position.X += 30 * g.MM
+	odo.Update(feet)
+	if odo.World != start {
+		t.Errorf("unplanted: got %v; exp %v", odo.World, start)
+	}
+
+	// a single planted foot only moves the position
+	feet[0].Planted = true
+	odo.Update(feet)
+	feet[0].Position.X -= 20 * g.MM
+	odo.Update(feet)
+
+	exp := start.Move(g.Vec{X: 20 * g.MM}, 0, 1)
+	if odo.World.Yaw != start.Yaw || odo.World.Position.Distance(exp.Position) > 0.01*g.MM {
+		t.Errorf("single: got %v; exp %v", odo.World, exp)
+	}
+}
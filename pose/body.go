@@ -1,11 +1,21 @@
 package pose
 
-import "github.com/egonelbre/hexapod/g"
+import (
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/terrain"
+)
 
 type Body struct {
+	// World is the pose of body ground space in the world,
+	// everything else is relative to body ground space.
+	World Ground `json:"-"`
+	// Terrain is the ground in world space, nil means the plane Y=0.
+	Terrain terrain.Terrain `json:"-"`
+
 	Size   g.Vec
 	Origin g.Vec
 	Orient g.Orient
+	Mass   Mass // relative to Origin, the body is a solid box of Size
 	Head   Head
 	Leg    Legs
 }
@@ -19,6 +29,7 @@ func (body *Body) Legs() []*Leg {
 
 type Head struct {
 	Offset g.Vec
+	Mass   Mass // relative to Offset
 }
 
 type Legs struct {
@@ -32,12 +43,13 @@ type Leg struct {
 	Phase g.Radians
 
 	Offset g.Vec // relative to body Origin
+	Knee   Knee  // preferred IK solution
 	Coxa   Hinge
 	Femur  Hinge
 	Tibia  Hinge
 
 	// Mainly for debug purposes
-	IK LegIK
+	IK LegIK `json:"-"`
 }
 
 type LegIK struct {
@@ -57,6 +69,16 @@ func (leg *Leg) Hinges() []*Hinge {
 	}
 }
 
+// Knee selects between the two IK solutions of Femur and Tibia.
+type Knee byte
+
+const (
+	KneeUp = Knee(iota)
+	KneeDown
+	// KneeAuto picks the solution that keeps hinges in Range.
+	KneeAuto
+)
+
 type Axis byte
 
 const (
@@ -65,16 +87,42 @@ const (
 	Z
 )
 
+// Vector returns a vector with the given length along the axis.
+func (axis Axis) Vector(length g.Length) g.Vec {
+	switch axis {
+	case X:
+		return g.Vec{X: length}
+	case Y:
+		return g.Vec{Y: length}
+	case Z:
+		return g.Vec{Z: length}
+	}
+	panic("invalid axis")
+}
+
 type Hinge struct {
 	// const
 	Axis   Axis
 	Zero   g.Radians
 	Length g.Length
+	Offset g.Vec // of the link end, in addition to Length along X
 	Range  HingeRange
 	Speed  g.Radians // per second
+	Mass   Mass      // of the link, relative to the hinge after rotation
 
 	// runtime
-	Angle g.Radians
+	Angle g.Radians `json:"-"`
+}
+
+// End returns the end of the link in hinge space.
+func (hinge *Hinge) End() g.Vec {
+	return hinge.Offset.Add(g.Vec{X: hinge.Length})
+}
+
+// Mass describes the mass of a rigid segment.
+type Mass struct {
+	Kilograms float32
+	Center    g.Vec // center of mass
 }
 
 type HingeRange struct {
@@ -105,6 +153,7 @@ func (hinge *Hinge) Clamp() bool {
 	return false
 }
 
+// VectorPlanted checks whether v is on the plane Y=0.
 func VectorPlanted(v g.Vec) bool {
 	return g.Abs(v.Y) < 1*g.MM
 }
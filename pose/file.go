@@ -0,0 +1,132 @@
+package pose
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// LoadBody reads a robot description from a JSON file.
+func LoadBody(path string) (*Body, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	body, err := ReadBody(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return body, nil
+}
+
+// ReadBody reads a robot description as JSON.
+//
+// Lengths are in millimeters and angles in radians. Leg IK targets
+// start under the leg offsets.
+func ReadBody(r io.Reader) (*Body, error) {
+	body := &Body{}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(body); err != nil {
+		return nil, err
+	}
+	if err := body.Validate(); err != nil {
+		return nil, err
+	}
+
+	for _, leg := range body.Legs() {
+		leg.IK.Origin = leg.Offset
+		leg.IK.Target = leg.Offset
+		leg.IK.Target.Y = 0
+	}
+	return body, nil
+}
+
+// SaveBody writes the robot description to a JSON file.
+func SaveBody(path string, body *Body) error {
+	data, err := json.MarshalIndent(body, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Validate checks that the description is usable.
+func (body *Body) Validate() error {
+	var errs []error
+	if body.Size.X <= 0 || body.Size.Y <= 0 || body.Size.Z <= 0 {
+		errs = append(errs, fmt.Errorf("invalid size %v", body.Size))
+	}
+
+	names := map[string]bool{}
+	for _, leg := range body.Legs() {
+		if leg.Name == "" {
+			errs = append(errs, errors.New("leg without a name"))
+		} else if names[leg.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate leg name", leg.Name))
+		}
+		names[leg.Name] = true
+
+		if leg.Knee > KneeAuto {
+			errs = append(errs, fmt.Errorf("%s: invalid knee %d", leg.Name, leg.Knee))
+		}
+		for i, hinge := range leg.Hinges() {
+			if hinge.Length < 0 {
+				errs = append(errs, fmt.Errorf("%s hinge %d: negative length", leg.Name, i))
+			}
+			if hinge.Speed < 0 {
+				errs = append(errs, fmt.Errorf("%s hinge %d: negative speed", leg.Name, i))
+			}
+		}
+		if leg.Coxa.Axis != Y || leg.Femur.Axis != Z || leg.Tibia.Axis != Z {
+			errs = append(errs, fmt.Errorf("%s: unsupported hinge axes, expected Y, Z, Z", leg.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var axisNames = [...]string{X: "X", Y: "Y", Z: "Z"}
+
+// MarshalText encodes the axis as "X", "Y" or "Z".
+func (axis Axis) MarshalText() ([]byte, error) {
+	if int(axis) >= len(axisNames) {
+		return nil, fmt.Errorf("invalid axis %d", axis)
+	}
+	return []byte(axisNames[axis]), nil
+}
+
+// UnmarshalText decodes the axis from "X", "Y" or "Z".
+func (axis *Axis) UnmarshalText(text []byte) error {
+	for i, name := range axisNames {
+		if name == string(text) {
+			*axis = Axis(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid axis %q", text)
+}
+
+var kneeNames = [...]string{KneeUp: "up", KneeDown: "down", KneeAuto: "auto"}
+
+// MarshalText encodes the knee as "up", "down" or "auto".
+func (knee Knee) MarshalText() ([]byte, error) {
+	if int(knee) >= len(kneeNames) {
+		return nil, fmt.Errorf("invalid knee %d", knee)
+	}
+	return []byte(kneeNames[knee]), nil
+}
+
+// UnmarshalText decodes the knee from "up", "down" or "auto".
+func (knee *Knee) UnmarshalText(text []byte) error {
+	for i, name := range kneeNames {
+		if name == string(text) {
+			*knee = Knee(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid knee %q", text)
+}
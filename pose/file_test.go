@@ -0,0 +1,69 @@
+package pose_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "robot.json")
+	if err := pose.SaveBody(path, adeept.ZeroPose()); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := pose.LoadBody(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := adeept.ZeroPose(); !reflect.DeepEqual(body, exp) {
+		t.Errorf("got %+v\nexp %+v", body, exp)
+	}
+}
+
+func TestAdeeptDescription(t *testing.T) {
+	body, err := pose.LoadBody("../adeept/robot.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := adeept.ZeroPose(); !reflect.DeepEqual(body, exp) {
+		t.Errorf("adeept/robot.json differs from adeept.ZeroPose")
+	}
+
+	// the file is exactly what SaveBody writes
+	path := filepath.Join(t.TempDir(), "robot.json")
+	if err := pose.SaveBody(path, adeept.ZeroPose()); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing, err := os.ReadFile("../adeept/robot.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(saved, existing) {
+		t.Errorf("adeept/robot.json is not formatted by SaveBody")
+	}
+}
+
+func TestReadBodyInvalid(t *testing.T) {
+	cases := []string{
+		`{"Size": {"X": 1, "Y": 1, "Z": 1}, "Leg": {"LF": {"Coxa": {"Axis": "W"}}}}`,
+		`{"Size": {"X": 1, "Y": 1, "Z": 1}, "Leg": {"LF": {"Knee": "sideways"}}}`,
+		`{"Size": {"X": 1, "Y": 1, "Z": 1}, "Wings": 2}`,
+		`{"Size": {"X": 1, "Y": 1, "Z": 1}}`,
+	}
+	for _, c := range cases {
+		if _, err := pose.ReadBody(strings.NewReader(c)); err == nil {
+			t.Errorf("%s: expected an error", c)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package pose
+
+import "github.com/egonelbre/hexapod/g"
+
+// Ground is the position and heading of the body on the ground.
+type Ground struct {
+	Position g.Vec // Y is ignored
+	Yaw      g.Radians
+}
+
+// World converts p from body ground space to world space.
+func (ground Ground) World(p g.Vec) g.Vec {
+	p = g.RotateY(ground.Yaw).Transform(p)
+	return g.Vec{X: ground.Position.X + p.X, Y: p.Y, Z: ground.Position.Z + p.Z}
+}
+
+// Local converts p from world space to body ground space.
+func (ground Ground) Local(p g.Vec) g.Vec {
+	p = g.Vec{X: p.X - ground.Position.X, Y: p.Y, Z: p.Z - ground.Position.Z}
+	return g.RotateY(-ground.Yaw).Transform(p)
+}
+
+// Lerp interpolates between two poses, turning the shorter way.
+func (ground Ground) Lerp(to Ground, t float32) Ground {
+	return Ground{
+		Position: ground.Position.Lerp(to.Position, t),
+		Yaw:      ground.Yaw + Turn(ground.Yaw, to.Yaw)*t,
+	}
+}
+
+// Move moves the pose by velocity and turn rate in body ground space,
+// over dt seconds.
+//
+// The rotation is applied first, such that ground points move the same
+// way as they do in gait.Engine.
+func (ground Ground) Move(velocity g.Vec, turnRate g.Radians, dt float32) Ground {
+	ground.Yaw += turnRate * dt
+	ground.Position = ground.Position.Add(g.RotateY(ground.Yaw).Transform(velocity.Scale(dt)))
+	ground.Position.Y = 0
+	return ground
+}
+
+// Turn returns the shortest rotation from a to b.
+func Turn(a, b g.Radians) g.Radians {
+	delta := b - a
+	for delta > g.Tau/2 {
+		delta -= g.Tau
+	}
+	for delta <= -g.Tau/2 {
+		delta += g.Tau
+	}
+	return delta
+}
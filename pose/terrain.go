@@ -0,0 +1,32 @@
+package pose
+
+import (
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/terrain"
+)
+
+// Ground returns the terrain in body ground space.
+//
+// When body.Terrain is nil, the ground is the plane Y=0.
+func (body *Body) Ground() terrain.Terrain {
+	if body.Terrain == nil {
+		return terrain.Flat{}
+	}
+	return local{world: body.World, terrain: body.Terrain}
+}
+
+// local converts terrain queries from body ground space to world space.
+type local struct {
+	world   Ground
+	terrain terrain.Terrain
+}
+
+func (local local) Height(x, z g.Length) g.Length {
+	p := local.world.World(g.Vec{X: x, Z: z})
+	return local.terrain.Height(p.X, p.Z)
+}
+
+func (local local) Normal(x, z g.Length) g.Vec {
+	p := local.world.World(g.Vec{X: x, Z: z})
+	return g.RotateY(-local.world.Yaw).Transform(local.terrain.Normal(p.X, p.Z))
+}
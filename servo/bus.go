@@ -0,0 +1,57 @@
+package servo
+
+// Bus is an I2C bus.
+type Bus interface {
+	// Write writes data to the device at address.
+	Write(address uint16, data []byte) error
+}
+
+// FakeBus is an in-memory bus that records writes.
+//
+// The first byte of a write is treated as a register, the rest of
+// the data is written to consecutive registers.
+type FakeBus struct {
+	Writes    []Write
+	Registers map[uint16]*[256]byte
+}
+
+// Write is a single recorded write.
+type Write struct {
+	Address uint16
+	Data    []byte
+}
+
+// Write records the write and updates the registers.
+func (bus *FakeBus) Write(address uint16, data []byte) error {
+	bus.Writes = append(bus.Writes, Write{
+		Address: address,
+		Data:    append([]byte(nil), data...),
+	})
+	if len(data) == 0 {
+		return nil
+	}
+
+	if bus.Registers == nil {
+		bus.Registers = map[uint16]*[256]byte{}
+	}
+	registers, ok := bus.Registers[address]
+	if !ok {
+		registers = &[256]byte{}
+		bus.Registers[address] = registers
+	}
+
+	register := data[0]
+	for _, v := range data[1:] {
+		registers[register] = v
+		register++
+	}
+	return nil
+}
+
+// Register returns the last value written to a register of the device.
+func (bus *FakeBus) Register(address uint16, register byte) byte {
+	if registers, ok := bus.Registers[address]; ok {
+		return registers[register]
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package servo
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// i2cSlave is the ioctl for selecting the device address.
+const i2cSlave = 0x0703
+
+// DeviceBus is an I2C bus using the Linux i2c-dev interface.
+type DeviceBus struct {
+	file     *os.File
+	address  uint16
+	selected bool
+}
+
+// OpenBus opens an I2C bus, e.g. "/dev/i2c-1".
+func OpenBus(path string) (*DeviceBus, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &DeviceBus{file: file}, nil
+}
+
+// Write writes data to the device at address.
+func (bus *DeviceBus) Write(address uint16, data []byte) error {
+	if !bus.selected || bus.address != address {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, bus.file.Fd(), i2cSlave, uintptr(address))
+		if errno != 0 {
+			return fmt.Errorf("select device %#x: %w", address, errno)
+		}
+		bus.address, bus.selected = address, true
+	}
+
+	n, err := bus.file.Write(data)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return fmt.Errorf("short write to %#x: %d of %d", address, n, len(data))
+	}
+	return nil
+}
+
+// Close closes the bus.
+func (bus *DeviceBus) Close() error {
+	return bus.file.Close()
+}
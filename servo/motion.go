@@ -0,0 +1,183 @@
+package servo
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+// Motion models how servos follow commanded angles.
+//
+// Hinges turn at most at Hinge.Speed, a zero speed means no limit.
+type Motion struct {
+	// Acceleration limits the change of hinge speed, per second²,
+	// zero means no limit.
+	Acceleration g.Radians
+	// Deadband is the smallest change of angle the servos respond to.
+	Deadband g.Radians
+
+	// Angles and Speeds are the servo states, in the same order as
+	// body.Legs() and leg.Hinges().
+	Angles [6][3]g.Radians
+	Speeds [6][3]g.Radians
+
+	// previous commands, for detecting infeasible ones
+	commands      [6][3]g.Radians
+	commandSpeeds [6][3]g.Radians
+
+	initialized bool
+}
+
+// Lag is a hinge whose command changed faster than the servo can follow.
+type Lag struct {
+	Leg       string
+	Hinge     int
+	Commanded g.Radians
+	Actual    g.Radians
+}
+
+func (lag Lag) String() string {
+	hinge := fmt.Sprintf("hinge %d", lag.Hinge)
+	if lag.Hinge >= 0 && lag.Hinge < len(hingeNames) {
+		hinge = hingeNames[lag.Hinge]
+	}
+	return fmt.Sprintf("%s %s lags %.1f°", lag.Leg, hinge, (lag.Commanded-lag.Actual)*g.RadToDeg)
+}
+
+var hingeNames = [3]string{"coxa", "femur", "tibia"}
+
+// Update moves the servos towards the commanded hinge angles for
+// dt seconds and replaces the hinge angles with the reached ones.
+//
+// Update returns the hinges where the command moved faster than
+// Hinge.Speed or changed speed faster than Acceleration. A hinge that
+// is still catching up with a feasible command is not a lag.
+func (motion *Motion) Update(body *pose.Body, dt float32) []Lag {
+	var lags []Lag
+	for i, leg := range body.Legs() {
+		for k, hinge := range leg.Hinges() {
+			commanded := hinge.Angle
+			if !motion.initialized {
+				motion.Angles[i][k] = commanded
+				motion.commands[i][k] = commanded
+				motion.commandSpeeds[i][k] = 0
+			}
+			infeasible := motion.infeasible(i, k, commanded, hinge.Speed, dt)
+
+			angle, speed := &motion.Angles[i][k], &motion.Speeds[i][k]
+			motion.step(angle, speed, commanded, hinge.Speed, dt)
+			hinge.Angle = *angle
+
+			if infeasible {
+				lags = append(lags, Lag{
+					Leg:       leg.Name,
+					Hinge:     k,
+					Commanded: commanded,
+					Actual:    *angle,
+				})
+			}
+		}
+	}
+	motion.initialized = true
+	return lags
+}
+
+// Reset makes the servos reach the current hinge angles immediately.
+func (motion *Motion) Reset() {
+	motion.initialized = false
+	motion.Speeds = [6][3]g.Radians{}
+}
+
+// infeasible checks whether the command of leg i hinge k moves faster
+// than limit or changes speed faster than Acceleration allows.
+func (motion *Motion) infeasible(i, k int, commanded, limit g.Radians, dt float32) bool {
+	const tolerance = 1e-4
+
+	previous, previousSpeed := motion.commands[i][k], motion.commandSpeeds[i][k]
+	motion.commands[i][k] = commanded
+	if dt <= 0 {
+		return false
+	}
+
+	delta := commanded - previous
+	if g.Abs(delta) <= motion.Deadband {
+		motion.commandSpeeds[i][k] = 0
+		return false
+	}
+	speed := delta / dt
+	motion.commandSpeeds[i][k] = speed
+
+	if limit > 0 && g.Abs(delta) > limit*dt+tolerance {
+		return true
+	}
+	if motion.Acceleration > 0 && g.Abs(speed-previousSpeed) > motion.Acceleration*dt+tolerance/dt {
+		return true
+	}
+	return false
+}
+
+// step moves a single servo towards target.
+func (motion *Motion) step(angle, speed *g.Radians, target, limit g.Radians, dt float32) {
+	delta := target - *angle
+	if dt <= 0 || *speed == 0 && g.Abs(delta) <= motion.Deadband {
+		return
+	}
+
+	// desired speed, slowing down in time to stop at the target
+	desired := delta / dt
+	if motion.Acceleration > 0 {
+		stopping := g.Radians(math.Sqrt(float64(2 * motion.Acceleration * g.Abs(delta))))
+		desired = g.Clamp(desired, -stopping, stopping)
+	}
+	if limit > 0 {
+		desired = g.Clamp(desired, -limit, limit)
+	}
+	if motion.Acceleration > 0 {
+		change := motion.Acceleration * dt
+		desired = g.Clamp(desired, *speed-change, *speed+change)
+	}
+
+	step := desired * dt
+	if g.Abs(step) >= g.Abs(delta) && step*delta >= 0 {
+		*angle, *speed = target, 0
+		return
+	}
+	*angle += step
+	*speed = desired
+}
+
+// Limited rate limits hinge angles with Motion before driving the servos.
+//
+// The hinge angles of the body are replaced with the limited ones.
+type Limited struct {
+	Driver Driver
+	Motion *Motion
+	// Lags are the hinges that lagged behind in the last Drive.
+	Lags []Lag
+
+	// Now returns the current time, nil means time.Now.
+	Now  func() time.Time
+	last time.Time
+}
+
+// Drive limits the hinge angles by the time since the previous Drive.
+func (limited *Limited) Drive(body *pose.Body) error {
+	now := time.Now
+	if limited.Now != nil {
+		now = limited.Now
+	}
+
+	t := now()
+	if limited.last.IsZero() {
+		limited.Motion.Reset()
+		limited.last = t
+	}
+	dt := float32(t.Sub(limited.last).Seconds())
+	limited.last = t
+
+	limited.Lags = limited.Motion.Update(body, dt)
+	return limited.Driver.Drive(body)
+}
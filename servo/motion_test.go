@@ -0,0 +1,135 @@
+package servo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/pose"
+	"github.com/egonelbre/hexapod/servo"
+)
+
+func TestMotionSpeed(t *testing.T) {
+	body := adeept.ZeroPose()
+	motion := &servo.Motion{}
+	motion.Update(body, 0)
+
+	speed := body.Leg.RF.Coxa.Speed
+	const dt = 1.0 / 50
+
+	body.Leg.RF.Coxa.Angle = g.Tau / 8
+	lags := motion.Update(body, dt)
+	if got, exp := body.Leg.RF.Coxa.Angle, speed*dt; g.Abs(got-exp) > 1e-5 {
+		t.Errorf("angle got %v; exp %v", got, exp)
+	}
+	if len(lags) != 1 || lags[0].Leg != "RF" || lags[0].Hinge != 0 || lags[0].Commanded != g.Tau/8 {
+		t.Errorf("lags got %v", lags)
+	}
+
+	// catching up with a held command is not a lag
+	body.Leg.RF.Coxa.Angle = g.Tau / 8
+	if lags := motion.Update(body, dt); body.Leg.RF.Coxa.Angle == g.Tau/8 || len(lags) != 0 {
+		t.Errorf("catching up: angle got %v, lags %v", body.Leg.RF.Coxa.Angle, lags)
+	}
+
+	// reaches the target in Tau/8 / speed = 75ms
+	for frame := 0; frame < 3; frame++ {
+		body.Leg.RF.Coxa.Angle = g.Tau / 8
+		lags = motion.Update(body, dt)
+	}
+	if body.Leg.RF.Coxa.Angle != g.Tau/8 || len(lags) != 0 {
+		t.Errorf("angle got %v, lags %v", body.Leg.RF.Coxa.Angle, lags)
+	}
+
+	// commands within the speed limit are followed without lags
+	for frame := 1; frame <= 10; frame++ {
+		body.Leg.RF.Coxa.Angle = g.Tau/8 - speed*dt*0.9*g.Radians(frame)
+		want := body.Leg.RF.Coxa.Angle
+		if lags := motion.Update(body, dt); body.Leg.RF.Coxa.Angle != want || len(lags) != 0 {
+			t.Errorf("frame %d: angle got %v; exp %v, lags %v", frame, body.Leg.RF.Coxa.Angle, want, lags)
+		}
+	}
+}
+
+func TestMotionAcceleration(t *testing.T) {
+	body := adeept.ZeroPose()
+	motion := &servo.Motion{Acceleration: g.Tau * 10, Deadband: g.Tau / 360}
+	motion.Update(body, 0)
+
+	const dt = 1.0 / 200
+	var previous, speed g.Radians
+	for frame := 0; frame < 200; frame++ {
+		body.Leg.LM.Femur.Angle = g.Tau / 8
+		motion.Update(body, dt)
+
+		angle := body.Leg.LM.Femur.Angle
+		next := (angle - previous) / dt
+		// the last step snaps to the target
+		if angle != g.Tau/8 && g.Abs(next-speed) > motion.Acceleration*dt+1e-3 {
+			t.Fatalf("frame %d: speed changed from %v to %v", frame, speed, next)
+		}
+		if angle > g.Tau/8 {
+			t.Fatalf("frame %d: overshot %v", frame, angle)
+		}
+		previous, speed = angle, next
+	}
+	if body.Leg.LM.Femur.Angle != g.Tau/8 {
+		t.Errorf("angle got %v; exp %v", body.Leg.LM.Femur.Angle, g.Tau/8)
+	}
+
+	// small changes are ignored
+	body.Leg.LM.Femur.Angle = g.Tau/8 + g.Tau/720
+	if lags := motion.Update(body, dt); body.Leg.LM.Femur.Angle != g.Tau/8 || len(lags) != 0 {
+		t.Errorf("deadband: angle got %v, lags %v", body.Leg.LM.Femur.Angle, lags)
+	}
+}
+
+// recorder records the RF tibia angle on every drive.
+type recorder struct{ angles []g.Radians }
+
+func (rec *recorder) Drive(body *pose.Body) error {
+	rec.angles = append(rec.angles, body.Leg.RF.Tibia.Angle)
+	return nil
+}
+
+func TestLimited(t *testing.T) {
+	rec := &recorder{}
+	now := time.Unix(0, 0)
+	limited := &servo.Limited{
+		Driver: rec,
+		Motion: &servo.Motion{},
+		Now:    func() time.Time { return now },
+	}
+
+	body := adeept.ZeroPose()
+	speed := body.Leg.RF.Tibia.Speed
+	for frame := 0; frame < 3; frame++ {
+		body.Leg.RF.Tibia.Angle = g.Tau / 4
+		if err := limited.Drive(body); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(10 * time.Millisecond)
+	}
+
+	// the first drive starts from the command
+	if len(rec.angles) != 3 || rec.angles[0] != g.Tau/4 || len(limited.Lags) != 0 {
+		t.Errorf("got %v, lags %v", rec.angles, limited.Lags)
+	}
+
+	for frame := 0; frame < 2; frame++ {
+		body.Leg.RF.Tibia.Angle = 0
+		if err := limited.Drive(body); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(10 * time.Millisecond)
+
+		// only the jump is infeasible
+		if exp := 1 - frame; len(limited.Lags) != exp {
+			t.Errorf("frame %d: lags got %v; exp %d", frame, limited.Lags, exp)
+		}
+	}
+	if got, exp := rec.angles[len(rec.angles)-1], g.Tau/4-speed*0.02; g.Abs(got-exp) > 1e-4 {
+		t.Errorf("angle got %v; exp %v", got, exp)
+	}
+}
@@ -0,0 +1,83 @@
+package servo
+
+import (
+	"fmt"
+	"time"
+)
+
+// PCA9685 registers.
+const (
+	pcaMode1    = 0x00
+	pcaMode2    = 0x01
+	pcaLED0     = 0x06 // ON_L, ON_H, OFF_L, OFF_H for each channel
+	pcaPrescale = 0xFE
+
+	pcaSleep         = 0x10
+	pcaAutoIncrement = 0x20
+	pcaRestart       = 0x80
+	pcaTotemPole     = 0x04
+
+	pcaOscillator = 25_000_000
+	pcaSteps      = 4096
+)
+
+// PCA9685 is a 16 channel PWM controller on an I2C bus.
+type PCA9685 struct {
+	Bus     Bus
+	Address uint16
+	// Frequency is the PWM frequency in Hz.
+	Frequency float64
+}
+
+// PCA9685Channels is the number of channels on a PCA9685.
+const PCA9685Channels = 16
+
+// DefaultPCA9685Address is the address with no address pins set.
+const DefaultPCA9685Address = 0x40
+
+// NewPCA9685 configures the controller for servo pulses at 50Hz.
+func NewPCA9685(bus Bus, address uint16) (*PCA9685, error) {
+	pca := &PCA9685{Bus: bus, Address: address, Frequency: 50}
+	if err := pca.init(); err != nil {
+		return nil, fmt.Errorf("PCA9685 %#x: %w", address, err)
+	}
+	return pca, nil
+}
+
+func (pca *PCA9685) init() error {
+	prescale := byte(pcaOscillator/(pcaSteps*pca.Frequency) + 0.5 - 1)
+
+	// the prescaler can only be changed while sleeping
+	if err := pca.write(pcaMode1, pcaSleep); err != nil {
+		return err
+	}
+	if err := pca.write(pcaPrescale, prescale); err != nil {
+		return err
+	}
+	if err := pca.write(pcaMode2, pcaTotemPole); err != nil {
+		return err
+	}
+	if err := pca.write(pcaMode1, pcaAutoIncrement); err != nil {
+		return err
+	}
+	// the oscillator needs 500µs to stabilize
+	time.Sleep(500 * time.Microsecond)
+	return pca.write(pcaMode1, pcaAutoIncrement|pcaRestart)
+}
+
+// SetPulse sets the pulse width of a channel.
+func (pca *PCA9685) SetPulse(channel int, width time.Duration) error {
+	if channel < 0 || channel >= PCA9685Channels {
+		return fmt.Errorf("PCA9685 %#x: channel %d out of range", pca.Address, channel)
+	}
+
+	period := time.Duration(float64(time.Second) / pca.Frequency)
+	off := int(float64(width)/float64(period)*pcaSteps + 0.5)
+	off = min(max(off, 0), pcaSteps-1)
+
+	return pca.write(byte(pcaLED0+4*channel), 0, 0, byte(off), byte(off>>8))
+}
+
+func (pca *PCA9685) write(register byte, values ...byte) error {
+	return pca.Bus.Write(pca.Address, append([]byte{register}, values...))
+}
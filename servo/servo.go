@@ -0,0 +1,81 @@
+// package servo drives hobby servos from hinge angles
+package servo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/pose"
+)
+
+// Driver moves servos to the hinge angles of a body.
+type Driver interface {
+	Drive(body *pose.Body) error
+}
+
+// PWM outputs servo pulses on numbered channels.
+type PWM interface {
+	SetPulse(channel int, width time.Duration) error
+}
+
+// Servo maps a hinge angle to a pulse width.
+type Servo struct {
+	Channel int
+	// Center is the pulse width at hinge angle 0.
+	Center time.Duration
+	// PerRadian is the change of the pulse width per radian.
+	PerRadian time.Duration
+	// Min and Max limit the pulse width.
+	Min, Max time.Duration
+}
+
+// SG92R is the mapping for a SG92R micro servo, 500µs to 2500µs for 180°.
+var SG92R = Servo{
+	Center:    1500 * time.Microsecond,
+	PerRadian: 636620 * time.Nanosecond, // 2000µs per 180°
+	Min:       500 * time.Microsecond,
+	Max:       2500 * time.Microsecond,
+}
+
+// Pulse returns the pulse width for angle, limited to Min and Max.
+func (servo *Servo) Pulse(angle g.Radians) time.Duration {
+	width := servo.Center + time.Duration(float32(servo.PerRadian)*float32(angle))
+	return min(max(width, servo.Min), servo.Max)
+}
+
+// Robot drives all hinges of a body, one servo per hinge.
+type Robot struct {
+	PWM PWM
+	// Servos are in the same order as body.Legs() and leg.Hinges().
+	Servos [6][3]Servo
+}
+
+// Drive sends the current hinge angles to the servos.
+func (robot *Robot) Drive(body *pose.Body) error {
+	for i, leg := range body.Legs() {
+		for k, hinge := range leg.Hinges() {
+			servo := &robot.Servos[i][k]
+			if err := robot.PWM.SetPulse(servo.Channel, servo.Pulse(hinge.Angle)); err != nil {
+				return fmt.Errorf("%s hinge %d: %w", leg.Name, k, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Chain combines several PWM outputs with Channels each,
+// e.g. channel 16 is the first channel of the second output.
+type Chain struct {
+	Outputs  []PWM
+	Channels int
+}
+
+// SetPulse sets the pulse on the output containing channel.
+func (chain *Chain) SetPulse(channel int, width time.Duration) error {
+	index := channel / chain.Channels
+	if channel < 0 || index >= len(chain.Outputs) {
+		return fmt.Errorf("channel %d out of range", channel)
+	}
+	return chain.Outputs[index].SetPulse(channel%chain.Channels, width)
+}
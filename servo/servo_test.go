@@ -0,0 +1,98 @@
+package servo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/servo"
+)
+
+func TestPulse(t *testing.T) {
+	s := servo.SG92R
+
+	type Case struct {
+		Angle g.Radians
+		Pulse time.Duration
+	}
+	cases := []Case{
+		{0, 1500 * time.Microsecond},
+		{g.Tau / 4, 2500 * time.Microsecond},
+		{-g.Tau / 8, 1000 * time.Microsecond},
+		{g.Tau / 2, 2500 * time.Microsecond},
+	}
+	for _, c := range cases {
+		if got := s.Pulse(c.Angle); (got - c.Pulse).Abs() > time.Microsecond {
+			t.Errorf("%v: got %v; exp %v", c.Angle, got, c.Pulse)
+		}
+	}
+}
+
+func TestPCA9685(t *testing.T) {
+	bus := &servo.FakeBus{}
+	pca, err := servo.NewPCA9685(bus, servo.DefaultPCA9685Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 25MHz / (4096 * 50Hz) - 1
+	if got := bus.Register(servo.DefaultPCA9685Address, 0xFE); got != 121 {
+		t.Errorf("prescale got %d; exp 121", got)
+	}
+
+	bus.Writes = nil
+	if err := pca.SetPulse(3, 1500*time.Microsecond); err != nil {
+		t.Fatal(err)
+	}
+	// 1.5ms of 20ms is 307 steps
+	exp := servo.Write{Address: 0x40, Data: []byte{0x06 + 4*3, 0, 0, 307 & 0xFF, 307 >> 8}}
+	if len(bus.Writes) != 1 || string(bus.Writes[0].Data) != string(exp.Data) || bus.Writes[0].Address != exp.Address {
+		t.Errorf("got %v; exp %v", bus.Writes, exp)
+	}
+
+	if err := pca.SetPulse(16, time.Millisecond); err == nil {
+		t.Errorf("expected error for channel 16")
+	}
+}
+
+func TestRobot(t *testing.T) {
+	bus := &servo.FakeBus{}
+	first, err := servo.NewPCA9685(bus, 0x40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := servo.NewPCA9685(bus, 0x41)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	robot := &servo.Robot{PWM: &servo.Chain{
+		Outputs:  []servo.PWM{first, second},
+		Channels: servo.PCA9685Channels,
+	}}
+	for i := range robot.Servos {
+		for k := range robot.Servos[i] {
+			robot.Servos[i][k] = servo.SG92R
+			robot.Servos[i][k].Channel = i*3 + k
+		}
+	}
+
+	body := adeept.ZeroPose()
+	body.Leg.LF.Tibia.Angle = g.Tau / 4
+	if err := robot.Drive(body); err != nil {
+		t.Fatal(err)
+	}
+
+	// LF is the last leg, tibia is on channel 17, the second channel of 0x41
+	ticks := func(address uint16, channel int) int {
+		register := byte(0x06 + 4*channel + 2)
+		return int(bus.Register(address, register)) | int(bus.Register(address, register+1))<<8
+	}
+	if got := ticks(0x41, 1); got != 512 {
+		t.Errorf("LF tibia got %d; exp 512", got)
+	}
+	if got := ticks(0x40, 0); got != 307 {
+		t.Errorf("RF coxa got %d; exp 307", got)
+	}
+}
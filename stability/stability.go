@@ -0,0 +1,70 @@
+// package stability analyses static stability of a pose
+package stability
+
+import (
+	"github.com/egonelbre/hexapod/fk"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/mass"
+	"github.com/egonelbre/hexapod/pose"
+	"github.com/egonelbre/hexapod/terrain"
+)
+
+// Analysis describes static stability of a pose.
+type Analysis struct {
+	// Feet are the planted feet in body ground space.
+	Feet []g.Vec
+	// Support is the convex hull of the planted feet.
+	Support g.Polygon
+	// CenterOfMass is the center of mass projected to the ground.
+	CenterOfMass g.Vec
+	// Margin is the distance from CenterOfMass to the nearest edge
+	// of the support polygon, negative when outside.
+	Margin g.Length
+	// Stable is whether the center of mass is inside the support polygon.
+	Stable bool
+}
+
+// Analyze calculates the static stability of the body.
+//
+// A foot is planted when it is meant to be planted and it is on the ground.
+func Analyze(body *pose.Body) Analysis {
+	var analysis Analysis
+	ground := body.Ground()
+	for _, leg := range body.Legs() {
+		foot := fk.Effector(body, leg)
+		if leg.IK.Planted && terrain.Planted(ground, foot) {
+			analysis.Feet = append(analysis.Feet, foot)
+		}
+	}
+
+	analysis.Support = g.ConvexHull(analysis.Feet)
+	analysis.CenterOfMass = CenterOfMass(body)
+	analysis.Margin = Margin(analysis.Support, analysis.CenterOfMass)
+	analysis.Stable = analysis.Margin > 0
+	return analysis
+}
+
+// CenterOfMass returns the center of mass of the body projected to the ground.
+//
+// Body origin is used when the body has no masses.
+func CenterOfMass(body *pose.Body) g.Vec {
+	center := body.Origin
+	if props := mass.Calculate(body); props.Kilograms > 0 {
+		center = props.Center
+	}
+	return g.Vec{X: center.X, Z: center.Z}
+}
+
+// Margin returns the distance from p to the nearest edge of support,
+// negative when p is outside of support.
+func Margin(support g.Polygon, p g.Vec) g.Length {
+	if len(support) == 0 {
+		return -g.M
+	}
+
+	distance := support.Distance(p)
+	if len(support) < 3 || !support.Contains(p) {
+		return -distance
+	}
+	return distance
+}
@@ -0,0 +1,94 @@
+package stability_test
+
+import (
+	"testing"
+
+	"github.com/egonelbre/hexapod/adeept"
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/gait"
+	"github.com/egonelbre/hexapod/ik/legik"
+	"github.com/egonelbre/hexapod/pose"
+	"github.com/egonelbre/hexapod/stability"
+)
+
+// stand plants the feet named in planted at their home positions
+// and lifts the others.
+func stand(body *pose.Body, planted ...string) {
+	for _, leg := range body.Legs() {
+		leg.IK.Target = gait.Home(leg)
+		leg.IK.Planted = false
+		for _, name := range planted {
+			if leg.Name == name {
+				leg.IK.Planted = true
+			}
+		}
+		if !leg.IK.Planted {
+			leg.IK.Target.Y = 20 * g.MM
+		}
+	}
+	legik.Solve(body)
+}
+
+func TestAnalyze(t *testing.T) {
+	type Case struct {
+		Name    string
+		Head    float32
+		Planted []string
+		Stable  bool
+	}
+	cases := []Case{
+		{"all", 0, []string{"LF", "LM", "LB", "RF", "RM", "RB"}, true},
+		{"tripod", 0, []string{"LF", "RM", "LB"}, true},
+		{"right", 0, []string{"RF", "RM", "RB"}, false},
+		{"front", 0, []string{"LF", "RF"}, false},
+		{"heavy head", 2, []string{"LM", "LB", "RM", "RB"}, false},
+	}
+
+	for _, c := range cases {
+		body := adeept.ZeroPose()
+		if c.Head > 0 {
+			body.Head.Mass.Kilograms = c.Head
+		}
+		stand(body, c.Planted...)
+
+		analysis := stability.Analyze(body)
+		if len(analysis.Feet) != len(c.Planted) {
+			t.Errorf("%s: got %d feet; exp %d", c.Name, len(analysis.Feet), len(c.Planted))
+		}
+		if analysis.Stable != c.Stable || (analysis.Margin > 0) != c.Stable {
+			t.Errorf("%s: got stable %v, margin %.3fmm", c.Name, analysis.Stable, analysis.Margin.Millimeters())
+		}
+		if analysis.CenterOfMass.Y != 0 {
+			t.Errorf("%s: center of mass not projected %v", c.Name, analysis.CenterOfMass)
+		}
+	}
+}
+
+func TestMargin(t *testing.T) {
+	square := g.ConvexHull([]g.Vec{
+		{X: 0, Z: 0},
+		{X: 100 * g.MM, Z: 100 * g.MM},
+		{X: 100 * g.MM, Z: 0},
+		{X: 50 * g.MM, Z: 50 * g.MM},
+		{X: 0, Z: 100 * g.MM},
+	})
+	if len(square) != 4 {
+		t.Fatalf("got hull %v", square)
+	}
+
+	type Case struct {
+		P      g.Vec
+		Margin g.Length
+	}
+	cases := []Case{
+		{g.Vec{X: 50 * g.MM, Z: 50 * g.MM}, 50 * g.MM},
+		{g.Vec{X: 10 * g.MM, Y: 30 * g.MM, Z: 50 * g.MM}, 10 * g.MM},
+		{g.Vec{X: 50 * g.MM, Z: 130 * g.MM}, -30 * g.MM},
+		{g.Vec{X: -30 * g.MM, Z: -40 * g.MM}, -50 * g.MM},
+	}
+	for _, c := range cases {
+		if got := stability.Margin(square, c.P); g.Abs(got-c.Margin) > 0.01*g.MM {
+			t.Errorf("%v: got %.3fmm; exp %.3fmm", c.P, got.Millimeters(), c.Margin.Millimeters())
+		}
+	}
+}
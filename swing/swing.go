@@ -0,0 +1,128 @@
+// package swing generates foot trajectories between lift-off and touch-down
+package swing
+
+import (
+	"github.com/egonelbre/hexapod/g"
+)
+
+// Curve is the profile of a swing.
+type Curve byte
+
+const (
+	// Cycloid moves like a point on a rolling wheel.
+	Cycloid Curve = iota
+	// MinimumJerk minimizes the change of acceleration, separately
+	// for lifting and lowering the foot.
+	MinimumJerk
+	// Bezier uses a cubic curve for the horizontal motion and a quartic
+	// curve for the vertical motion.
+	Bezier
+)
+
+func (curve Curve) String() string {
+	switch curve {
+	case Cycloid:
+		return "cycloid"
+	case MinimumJerk:
+		return "minimum-jerk"
+	case Bezier:
+		return "bezier"
+	default:
+		return "unknown"
+	}
+}
+
+// Shape describes how a foot swings.
+type Shape struct {
+	Curve Curve
+	// Height is how high the foot is lifted above the line between
+	// lift-off and touch-down.
+	Height g.Length
+
+	// LiftOff is the foot velocity when leaving the ground, per second.
+	LiftOff g.Vec
+	// TouchDown is the foot velocity when reaching the ground, per second.
+	TouchDown g.Vec
+}
+
+// Trajectory is a swing between two points on the ground.
+type Trajectory struct {
+	Shape
+	From, To g.Vec
+	// Duration is the duration of the swing in seconds.
+	Duration float32
+}
+
+// Between creates a trajectory from one point to another.
+func (shape Shape) Between(from, to g.Vec, duration float32) Trajectory {
+	return Trajectory{
+		Shape:    shape,
+		From:     from,
+		To:       to,
+		Duration: duration,
+	}
+}
+
+// At returns the foot position at swing progress s, [0, 1].
+func (traj *Trajectory) At(s float32) g.Vec {
+	s = min(max(s, 0), 1)
+
+	horizontal, vertical := traj.Curve.profile(s)
+	p := traj.From.Lerp(traj.To, horizontal)
+	p.Y += traj.Height.Scale(vertical)
+
+	// All curves start and end at rest, the Hermite basis functions
+	// add the desired velocities without moving the end points.
+	liftOff := s * (1 - s) * (1 - s)
+	touchDown := s * s * (s - 1)
+	p = p.Add(traj.LiftOff.Scale(liftOff * traj.Duration))
+	p = p.Add(traj.TouchDown.Scale(touchDown * traj.Duration))
+	return p
+}
+
+// Sample returns the foot position at swing progress s, where ground
+// is the height of the ground under the foot.
+//
+// The swing ends early when the foot reaches the ground while descending,
+// in which case the foot is placed on the ground and touched is true.
+func (traj *Trajectory) Sample(s float32, ground g.Length) (foot g.Vec, touched bool) {
+	if s >= 1 {
+		return traj.To, true
+	}
+
+	foot = traj.At(s)
+	if s > 0.5 && foot.Y <= ground {
+		foot.Y = ground
+		return foot, true
+	}
+	return foot, false
+}
+
+// profile returns the horizontal progress and the vertical lift at s,
+// both are in range [0, 1].
+func (curve Curve) profile(s float32) (horizontal, vertical float32) {
+	switch curve {
+	case MinimumJerk:
+		horizontal = minimumJerk(s)
+		if s < 0.5 {
+			vertical = minimumJerk(2 * s)
+		} else {
+			vertical = minimumJerk(2 - 2*s)
+		}
+	case Bezier:
+		// control points 0, 0, 1, 1
+		horizontal = s * s * (3 - 2*s)
+		// control points 0, 0, 8/3, 0, 0
+		vertical = 16 * s * s * (1 - s) * (1 - s)
+	default:
+		horizontal = s - g.Sin(g.Tau*s)/g.Tau
+		vertical = (1 - g.Cos(g.Tau*s)) / 2
+	}
+	return horizontal, vertical
+}
+
+// minimumJerk interpolates from 0 to 1 with zero velocity
+// and acceleration at the ends.
+func minimumJerk(s float32) float32 {
+	return s * s * s * (10 + s*(-15+6*s))
+}
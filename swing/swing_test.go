@@ -0,0 +1,87 @@
+package swing_test
+
+import (
+	"testing"
+
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/swing"
+)
+
+const tolerance = 0.01 * g.MM
+
+var curves = []swing.Curve{swing.Cycloid, swing.MinimumJerk, swing.Bezier}
+
+func TestEnds(t *testing.T) {
+	for _, curve := range curves {
+		shape := swing.Shape{
+			Curve:     curve,
+			Height:    20 * g.MM,
+			LiftOff:   g.Vec{Y: 50 * g.MM},
+			TouchDown: g.Vec{X: -30 * g.MM, Y: -50 * g.MM},
+		}
+		from := g.Vec{X: 100 * g.MM, Z: 50 * g.MM}
+		to := g.Vec{X: 140 * g.MM, Y: 10 * g.MM, Z: 60 * g.MM}
+		traj := shape.Between(from, to, 0.3)
+
+		if d := traj.At(0).Distance(from); d > tolerance {
+			t.Errorf("%v: start off by %.3fmm", curve, d.Millimeters())
+		}
+		if d := traj.At(1).Distance(to); d > tolerance {
+			t.Errorf("%v: end off by %.3fmm", curve, d.Millimeters())
+		}
+
+		// velocities at the ends, per second
+		const ds = 1e-3
+		liftOff := traj.At(ds).Sub(traj.At(0)).Scale(1 / (ds * traj.Duration))
+		touchDown := traj.At(1).Sub(traj.At(1 - ds)).Scale(1 / (ds * traj.Duration))
+		if d := liftOff.Distance(shape.LiftOff); d > 2*g.MM {
+			t.Errorf("%v: lift-off velocity got %v; exp %v", curve, liftOff, shape.LiftOff)
+		}
+		if d := touchDown.Distance(shape.TouchDown); d > 2*g.MM {
+			t.Errorf("%v: touch-down velocity got %v; exp %v", curve, touchDown, shape.TouchDown)
+		}
+	}
+}
+
+func TestHeight(t *testing.T) {
+	for _, curve := range curves {
+		shape := swing.Shape{Curve: curve, Height: 25 * g.MM}
+		traj := shape.Between(g.Vec{}, g.Vec{X: 50 * g.MM}, 0.5)
+
+		apex := traj.At(0.5)
+		if d := apex.Distance(g.Vec{X: 25 * g.MM, Y: 25 * g.MM}); d > tolerance {
+			t.Errorf("%v: apex got %v", curve, apex)
+		}
+
+		previous := traj.At(0)
+		for i := 1; i <= 100; i++ {
+			p := traj.At(float32(i) / 100)
+			if p.X < previous.X || p.Y < 0 || p.Y > shape.Height+tolerance {
+				t.Errorf("%v: invalid %v after %v", curve, p, previous)
+			}
+			previous = p
+		}
+	}
+}
+
+func TestEarlyTouchDown(t *testing.T) {
+	for _, curve := range curves {
+		shape := swing.Shape{Curve: curve, Height: 20 * g.MM}
+		traj := shape.Between(g.Vec{}, g.Vec{X: 50 * g.MM}, 0.5)
+
+		ground := 8 * g.MM
+		for i := 0; i <= 100; i++ {
+			s := float32(i) / 100
+			foot, touched := traj.Sample(s, ground)
+			if s <= 0.5 && touched {
+				t.Fatalf("%v: touched down while lifting at %v", curve, s)
+			}
+			if touched {
+				if s == 1 || foot.Y != ground {
+					t.Errorf("%v: touched down at %v, %v", curve, s, foot)
+				}
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// package terrain describes the ground the robot walks on
+package terrain
+
+import (
+	"fmt"
+
+	"github.com/egonelbre/hexapod/g"
+)
+
+// Terrain is the ground surface in world space.
+type Terrain interface {
+	// Height returns the height of the ground at x, z.
+	Height(x, z g.Length) g.Length
+	// Normal returns the unit surface normal at x, z,
+	// where the unit is g.Length(1).
+	Normal(x, z g.Length) g.Vec
+}
+
+// Flat is a horizontal plane at Level.
+type Flat struct {
+	Level g.Length
+}
+
+func (flat Flat) Height(x, z g.Length) g.Length { return flat.Level }
+func (flat Flat) Normal(x, z g.Length) g.Vec    { return g.Vec{Y: 1} }
+
+// Heightmap is a grid of heights, interpolated bilinearly.
+//
+// Heights are stored row by row, where rows are along Z and columns
+// along X. Outside of the grid the nearest edge is extended.
+type Heightmap struct {
+	// Origin is the position of the first sample, Y is ignored.
+	Origin g.Vec
+	// Cell is the distance between samples.
+	Cell g.Length
+
+	Columns, Rows int
+	Heights       []g.Length
+}
+
+// NewHeightmap creates a flat heightmap with the given size.
+//
+// The heightmap needs at least one sample and a positive Cell.
+func NewHeightmap(origin g.Vec, cell g.Length, columns, rows int) (*Heightmap, error) {
+	if columns < 1 || rows < 1 {
+		return nil, fmt.Errorf("terrain: invalid heightmap size %dx%d", columns, rows)
+	}
+	if cell <= 0 {
+		return nil, fmt.Errorf("terrain: invalid heightmap cell %v", cell)
+	}
+	return &Heightmap{
+		Origin:  origin,
+		Cell:    cell,
+		Columns: columns,
+		Rows:    rows,
+		Heights: make([]g.Length, columns*rows),
+	}, nil
+}
+
+// At returns the sample at column x and row z, clamped to the grid.
+// An empty grid is flat at zero.
+func (hm *Heightmap) At(x, z int) g.Length {
+	if hm.Columns < 1 || hm.Rows < 1 || len(hm.Heights) < hm.Columns*hm.Rows {
+		return 0
+	}
+	x = min(max(x, 0), hm.Columns-1)
+	z = min(max(z, 0), hm.Rows-1)
+	return hm.Heights[z*hm.Columns+x]
+}
+
+// Set sets the sample at column x and row z.
+func (hm *Heightmap) Set(x, z int, height g.Length) {
+	hm.Heights[z*hm.Columns+x] = height
+}
+
+// Height returns the interpolated height at x, z.
+func (hm *Heightmap) Height(x, z g.Length) g.Length {
+	fx := ((x - hm.Origin.X) / hm.Cell).Float32()
+	fz := ((z - hm.Origin.Z) / hm.Cell).Float32()
+	fx = min(max(fx, 0), float32(hm.Columns-1))
+	fz = min(max(fz, 0), float32(hm.Rows-1))
+
+	ix, iz := int(fx), int(fz)
+	tx, tz := fx-float32(ix), fz-float32(iz)
+
+	near := lerp(hm.At(ix, iz), hm.At(ix+1, iz), tx)
+	far := lerp(hm.At(ix, iz+1), hm.At(ix+1, iz+1), tx)
+	return lerp(near, far, tz)
+}
+
+// Normal returns the surface normal at x, z, using central differences.
+func (hm *Heightmap) Normal(x, z g.Length) g.Vec {
+	return Normal(hm, x, z, hm.Cell/2)
+}
+
+// Normal estimates the normal of terrain at x, z by sampling heights
+// at distance delta.
+func Normal(terrain Terrain, x, z, delta g.Length) g.Vec {
+	dx := terrain.Height(x+delta, z) - terrain.Height(x-delta, z)
+	dz := terrain.Height(x, z+delta) - terrain.Height(x, z-delta)
+	return g.Vec{X: -dx, Y: 2 * delta, Z: -dz}.NormalizedTo(1)
+}
+
+// Distance returns the signed distance of p from the surface,
+// measured along the normal, positive above the ground.
+func Distance(terrain Terrain, p g.Vec) g.Length {
+	height := terrain.Height(p.X, p.Z)
+	normal := terrain.Normal(p.X, p.Z)
+	return (p.Y - height).Scale(normal.Y.Float32())
+}
+
+// Planted checks whether p is on the surface.
+func Planted(terrain Terrain, p g.Vec) bool {
+	return g.Abs(Distance(terrain, p)) < 1*g.MM
+}
+
+// lerp interpolates between a and b.
+func lerp(a, b g.Length, t float32) g.Length {
+	return a + (b - a).Scale(t)
+}
@@ -0,0 +1,81 @@
+package terrain_test
+
+import (
+	"testing"
+
+	"github.com/egonelbre/hexapod/g"
+	"github.com/egonelbre/hexapod/terrain"
+)
+
+func TestHeightmap(t *testing.T) {
+	hm, err := terrain.NewHeightmap(g.Vec{X: -10 * g.MM, Z: -10 * g.MM}, 10*g.MM, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hm.Set(1, 0, 10*g.MM)
+	hm.Set(2, 0, 20*g.MM)
+	hm.Set(1, 1, 10*g.MM)
+	hm.Set(2, 1, 20*g.MM)
+
+	type Case struct {
+		X, Z   g.Length
+		Height g.Length
+	}
+	cases := []Case{
+		{-10 * g.MM, -10 * g.MM, 0},
+		{0, 0, 10 * g.MM},
+		{5 * g.MM, -5 * g.MM, 15 * g.MM},
+		// outside extends the edge
+		{100 * g.MM, 0, 20 * g.MM},
+		{-100 * g.MM, 100 * g.MM, 0},
+	}
+	for _, c := range cases {
+		if got := hm.Height(c.X, c.Z); g.Abs(got-c.Height) > 0.01*g.MM {
+			t.Errorf("%v %v: got %v; exp %v", c.X, c.Z, got, c.Height)
+		}
+	}
+
+	// slope of 1 along X
+	exp := g.Vec{X: -1, Y: 1}.NormalizedTo(1)
+	if got := hm.Normal(0, 0); got.Distance(exp) > 0.01 {
+		t.Errorf("normal got %v; exp %v", got, exp)
+	}
+}
+
+func TestHeightmapSize(t *testing.T) {
+	for _, size := range [][2]int{{0, 1}, {1, 0}, {-1, 3}} {
+		if _, err := terrain.NewHeightmap(g.Vec{}, 10*g.MM, size[0], size[1]); err == nil {
+			t.Errorf("%v: expected error", size)
+		}
+	}
+	if _, err := terrain.NewHeightmap(g.Vec{}, 0, 1, 1); err == nil {
+		t.Errorf("zero cell: expected error")
+	}
+
+	single, err := terrain.NewHeightmap(g.Vec{}, 10*g.MM, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	single.Set(0, 0, 5*g.MM)
+	if got := single.Height(20*g.MM, -20*g.MM); got != 5*g.MM {
+		t.Errorf("single got %v; exp %v", got, 5*g.MM)
+	}
+
+	var empty terrain.Heightmap
+	if got := empty.At(1, 1); got != 0 {
+		t.Errorf("empty got %v; exp 0", got)
+	}
+}
+
+func TestPlanted(t *testing.T) {
+	flat := terrain.Flat{Level: 30 * g.MM}
+	if !terrain.Planted(flat, g.Vec{X: 50 * g.MM, Y: 30.5 * g.MM}) {
+		t.Errorf("foot on the ground not planted")
+	}
+	if terrain.Planted(flat, g.Vec{Y: 0}) {
+		t.Errorf("foot below the ground planted")
+	}
+	if d := terrain.Distance(flat, g.Vec{Y: 50 * g.MM}); d != 20*g.MM {
+		t.Errorf("distance got %v; exp %v", d, 20*g.MM)
+	}
+}